@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fengshenyun/sansi/pkg/scrape"
+	"github.com/spf13/cobra"
+)
+
+var (
+	expiredOnly bool
+)
+
+func NewCacheCommand() *cobra.Command {
+	ac := &cobra.Command{
+		Use:   "cache <subcommand>",
+		Short: "Manage the http response cache.",
+	}
+
+	stats := &cobra.Command{
+		Use:   "stats [options]",
+		Short: "Show http cache entries and size.",
+		Run:   cacheStatsCommandFunc,
+	}
+
+	stats.Flags().StringVar(&rootPath, "root-path", "./data", "Set root path")
+	stats.Flags().StringVar(&outputFormat, "output", "table", "Set output format, table or json")
+
+	clear := &cobra.Command{
+		Use:   "clear [options]",
+		Short: "Remove http cache entries.",
+		Run:   cacheClearCommandFunc,
+	}
+
+	clear.Flags().StringVar(&rootPath, "root-path", "./data", "Set root path")
+	clear.Flags().BoolVar(&expiredOnly, "expired", false, "Only remove expired entries")
+
+	ac.AddCommand(stats, clear)
+	return ac
+}
+
+func cacheStatsCommandFunc(cmd *cobra.Command, args []string) {
+	cache := &scrape.HttpCache{Dir: scrape.GetCacheDir(rootPath)}
+
+	stats, err := cache.Stats()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if outputFormat == "json" {
+		printJson(stats)
+		return
+	}
+
+	fmt.Printf("dir:%v\nentries:%v\nexpired:%v\nsize:%v\n", stats.Dir, stats.Entries, stats.Expired, humanSize(stats.Size))
+}
+
+func cacheClearCommandFunc(cmd *cobra.Command, args []string) {
+	cache := &scrape.HttpCache{Dir: scrape.GetCacheDir(rootPath)}
+
+	count, size, err := cache.Clear(expiredOnly)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("removed %v entries, %v\n", count, humanSize(size))
+}
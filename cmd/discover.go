@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/fengshenyun/sansi/pkg/scrape"
+	"github.com/spf13/cobra"
+)
+
+var (
+	maxPages  int
+	queue     bool
+	queuePath string
+)
+
+func NewDiscoverCommand() *cobra.Command {
+	ac := &cobra.Command{
+		Use:   "discover <list-url> [options]",
+		Short: "Crawl a category or tag listing to discover comics.",
+		Args:  cobra.ExactArgs(1),
+		Run:   discoverCommandFunc,
+	}
+
+	addDownloadFlags(ac)
+	ac.Flags().IntVar(&maxPages, "max-pages", 0, "Set max listing pages to crawl, 0 means all")
+	ac.Flags().BoolVar(&queue, "queue", false, "Append discovered comics to the queue file instead of printing")
+	ac.Flags().StringVar(&queuePath, "queue-file", "", "Set queue file, default <root-path>/"+scrape.DefaultQueueName)
+	ac.Flags().StringVar(&outputFormat, "output", "table", "Set output format, table or json")
+
+	return ac
+}
+
+func discoverCommandFunc(cmd *cobra.Command, args []string) {
+	c := scrape.NewWithConfig(newDownloadConfig())
+
+	entries, err := c.Discover(args[0], maxPages)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if len(entries) == 0 {
+			os.Exit(1)
+		}
+	}
+
+	if queue {
+		if queuePath == "" {
+			queuePath = scrape.GetQueuePath(rootPath)
+		}
+
+		added, err := scrape.AppendQueue(queuePath, entries)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("discovered %v comics, %v queued to %v\n", len(entries), added, queuePath)
+		return
+	}
+
+	if outputFormat == "json" {
+		printJson(entries)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NUMBER\tTITLE\tURL\tCOVER")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", entry.Number, entry.Title, entry.Url, entry.CoverUrl)
+	}
+
+	w.Flush()
+}
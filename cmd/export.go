@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fengshenyun/sansi/pkg/scrape"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFormat string
+	volumeSize   int
+	comicName    string
+)
+
+func NewExportCommand() *cobra.Command {
+	ac := &cobra.Command{
+		Use:   "export [options]",
+		Short: "Export downloaded comic chapters.",
+		Run:   exportCommandFunc,
+	}
+
+	ac.Flags().StringVar(&url, "url", "", "Export target url")
+	ac.Flags().StringVar(&comicName, "comic", "", "Export downloaded comic by pinyin name, read from its manifest without network")
+	ac.Flags().StringVar(&rootPath, "root-path", "./data", "Set root path")
+	ac.Flags().StringVar(&profile, "profile", "", "Load site selector profile from yaml or json file")
+	ac.Flags().StringVar(&layout, "layout", "", "Set image layout on disk, chapter or cdn, default keeps the layout of an existing download or uses "+scrape.DefaultLayout)
+	ac.Flags().StringVar(&exportFormat, "format", scrape.ExportCbz, "Set export format, supported format: cbz, epub, pdf")
+	ac.Flags().IntVar(&volumeSize, "volume-size", 0, "Set number of chapters in one pdf, 0 means one pdf per chapter")
+
+	return ac
+}
+
+func exportCommandFunc(cmd *cobra.Command, args []string) {
+	sc := new(scrape.Config)
+	sc.Debug = globalFlags.Debug
+	sc.Url = url
+	sc.RootPath = rootPath
+	sc.Layout = layout
+	sc.Profile = profile
+	sc.VolumeSize = volumeSize
+
+	c, err := loadOrPrepare(sc)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	defer c.Close()
+
+	c.VolumeSize = volumeSize
+	if err = c.Export(exportFormat); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func loadOrPrepare(sc *scrape.Config) (*scrape.Comics, error) {
+	if comicName != "" {
+		return scrape.LoadManifest(scrape.GetManifestPath(sc.RootPath, comicName))
+	}
+
+	c := scrape.NewWithConfig(sc)
+	if err := c.Prepare(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
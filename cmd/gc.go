@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fengshenyun/sansi/pkg/scrape"
+	"github.com/spf13/cobra"
+)
+
+func NewGcCommand() *cobra.Command {
+	ac := &cobra.Command{
+		Use:   "gc [options]",
+		Short: "Remove blobs no longer referenced by any comic.",
+		Run:   gcCommandFunc,
+	}
+
+	ac.Flags().StringVar(&rootPath, "root-path", "./data", "Set root path")
+	ac.Flags().BoolVar(&dryRun, "dry-run", false, "Only report unreferenced blobs, remove nothing")
+
+	return ac
+}
+
+func gcCommandFunc(cmd *cobra.Command, args []string) {
+	s, err := scrape.OpenStore(scrape.GetStorePath(rootPath), "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	result, err := s.Gc(rootPath, dryRun)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	verb := "removed"
+	if dryRun {
+		verb = "would remove"
+	}
+
+	fmt.Printf("blobs:%v, %v:%v, freed:%v\n", result.Blobs, verb, result.Removed, humanSize(result.Freed))
+}
@@ -1,5 +1,22 @@
 package cmd
 
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
 type GlobalFlags struct {
 	Debug bool
 }
+
+func printJson(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
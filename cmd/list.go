@@ -1 +1,118 @@
 package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fengshenyun/sansi/pkg/scrape"
+	"github.com/spf13/cobra"
+)
+
+var (
+	listSort     string
+	listDesc     bool
+	listCategory string
+	outputFormat string
+)
+
+func NewListCommand() *cobra.Command {
+	ac := &cobra.Command{
+		Use:   "list [options]",
+		Short: "List downloaded comics in root path.",
+		Run:   listCommandFunc,
+	}
+
+	ac.Flags().StringVar(&rootPath, "root-path", "./data", "Set root path")
+	ac.Flags().StringVar(&listSort, "sort", "title", "Sort by title, pinyin, number, updated, chapters, images or size")
+	ac.Flags().BoolVar(&listDesc, "desc", false, "Sort in descending order")
+	ac.Flags().StringVar(&listCategory, "category", "", "Only list comics in this category")
+	ac.Flags().StringVar(&outputFormat, "output", "table", "Set output format, table or json")
+
+	return ac
+}
+
+func listCommandFunc(cmd *cobra.Command, args []string) {
+	entries, err := scrape.ScanLibrary(rootPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if listCategory != "" {
+		filtered := entries[:0]
+		for _, entry := range entries {
+			if entry.Category == listCategory {
+				filtered = append(filtered, entry)
+			}
+		}
+
+		entries = filtered
+	}
+
+	less, err := libraryLess(listSort)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if listDesc {
+			return less(entries[j], entries[i])
+		}
+
+		return less(entries[i], entries[j])
+	})
+
+	if outputFormat == "json" {
+		printJson(entries)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TITLE\tPINYIN\tNUMBER\tCATEGORY\tCHAPTERS(SITE/LOCAL)\tIMAGES\tSIZE\tLAST MODIFIED")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v/%v\t%v\t%v\t%v\n", entry.Title, entry.EnTitle, entry.Number, entry.Category,
+			entry.SiteChapters, entry.DownloadedChapters, entry.Images, humanSize(entry.Size), entry.LastModifyTime)
+	}
+
+	w.Flush()
+}
+
+func libraryLess(key string) (func(a, b *scrape.LibraryEntry) bool, error) {
+	switch strings.ToLower(key) {
+	case "title":
+		return func(a, b *scrape.LibraryEntry) bool { return a.Title < b.Title }, nil
+	case "pinyin":
+		return func(a, b *scrape.LibraryEntry) bool { return a.EnTitle < b.EnTitle }, nil
+	case "number":
+		return func(a, b *scrape.LibraryEntry) bool { return a.Number < b.Number }, nil
+	case "updated":
+		return func(a, b *scrape.LibraryEntry) bool { return a.LastModifyTime < b.LastModifyTime }, nil
+	case "chapters":
+		return func(a, b *scrape.LibraryEntry) bool { return a.SiteChapters < b.SiteChapters }, nil
+	case "images":
+		return func(a, b *scrape.LibraryEntry) bool { return a.Images < b.Images }, nil
+	case "size":
+		return func(a, b *scrape.LibraryEntry) bool { return a.Size < b.Size }, nil
+	}
+
+	return nil, fmt.Errorf("unsupported sort key:%v", key)
+}
+
+func humanSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f%ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fengshenyun/sansi/pkg/scrape"
+	"github.com/spf13/cobra"
+)
+
+func NewQueryCommand() *cobra.Command {
+	ac := &cobra.Command{
+		Use:   "query <report|sql> [options]",
+		Short: "Query the library database.",
+		Long: "Query the library database with a canned report or raw sql.\n" +
+			"Reports: " + strings.Join(scrape.ReportNames(), ", ") + "\n" +
+			"Tables: comics, chapters, images, scrapes",
+		Args: cobra.MinimumNArgs(1),
+		Run:  queryCommandFunc,
+	}
+
+	ac.Flags().StringVar(&rootPath, "root-path", "./data", "Set root path")
+	ac.Flags().StringVar(&outputFormat, "output", "table", "Set output format, table or json")
+
+	return ac
+}
+
+func queryCommandFunc(cmd *cobra.Command, args []string) {
+	query := strings.Join(args, " ")
+	if report, ok := scrape.LibraryReports[query]; ok {
+		query = report
+	}
+
+	dbPath := scrape.GetLibraryDBPath(rootPath)
+	if _, err := os.Stat(dbPath); err != nil {
+		fmt.Fprintln(os.Stderr, "no library db, scrape something first:", err)
+		os.Exit(1)
+	}
+
+	l, err := scrape.OpenLibraryDBReadOnly(dbPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	defer l.Close()
+
+	columns, rows, err := l.Query(query)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if outputFormat == "json" {
+		records := make([]map[string]string, 0, len(rows))
+		for _, row := range rows {
+			record := make(map[string]string, len(columns))
+			for i, column := range columns {
+				record[column] = row[i]
+			}
+
+			records = append(records, record)
+		}
+
+		printJson(records)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.ToUpper(strings.Join(columns, "\t")))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	w.Flush()
+}
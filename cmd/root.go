@@ -28,6 +28,16 @@ func init() {
 
 	rootCmd.AddCommand(
 		NewScrapeCommand(),
+		NewExportCommand(),
+		NewUpdateCommand(),
+		NewWatchCommand(),
+		NewListCommand(),
+		NewDiscoverCommand(),
+		NewCacheCommand(),
+		NewGcCommand(),
+		NewQueryCommand(),
+		NewSearchCommand(),
+		NewServeCommand(),
 	)
 }
 
@@ -1,42 +1,206 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/fengshenyun/sansi/pkg/scrape"
 	"github.com/spf13/cobra"
 )
 
 var (
-	url      string
-	rootPath string
-	timeout  int
+	url         string
+	rootPath    string
+	timeout     int
+	concurrency int
+	rateLimit   float64
+	layout      string
+	export      string
+	retries     int
+	retryDelay  time.Duration
+	readTimeout int
+	proxy       string
+	insecure    bool
+	profile     string
+	mirrors     []string
+	fromFile    string
+	jobs        int
+	chapters    string
+	dryRun      bool
+	useCache    bool
+	store       bool
+	storeLink   string
 )
 
 func NewScrapeCommand() *cobra.Command {
 	ac := &cobra.Command{
 		Use:   "scrape [options]",
-		Short: "Scrape one web url.",
+		Short: "Scrape one web url, or many urls listed in a file.",
 		Run:   scrapeCommandFunc,
 	}
 
 	ac.Flags().StringVar(&url, "url", "", "Scrape target url")
-	ac.Flags().IntVar(&timeout, "timeout", 10, "Set connect timeout")
-	ac.Flags().StringVar(&rootPath, "root-path", "./data", "Set root path")
+	ac.Flags().StringVar(&fromFile, "from-file", "", "Scrape urls listed in file, one per line, - for stdin")
+	ac.Flags().IntVar(&jobs, "jobs", scrape.DefaultBatchJobs, "Set number of comics scraped at the same time with --from-file")
+	addDownloadFlags(ac)
+	ac.Flags().StringVar(&chapters, "chapters", "", "Only scrape selected chapters, e.g. 1-10,45,70-")
+	ac.Flags().BoolVar(&dryRun, "dry-run", false, "Only report what would be downloaded, write nothing")
+	ac.Flags().StringVar(&outputFormat, "output", "table", "Set dry run output format, table or json")
+	ac.Flags().StringVar(&layout, "layout", "", "Set image layout on disk, chapter or cdn, default keeps the layout of an existing download or uses "+scrape.DefaultLayout)
+	ac.Flags().StringVar(&export, "export", "", "Export after scrape, supported format: cbz, epub, pdf")
+	ac.Flags().IntVar(&volumeSize, "volume-size", 0, "Set number of chapters in one pdf, 0 means one pdf per chapter")
 
 	return ac
 }
 
-func scrapeCommandFunc(cmd *cobra.Command, args []string) {
+func addDownloadFlags(ac *cobra.Command) {
+	ac.Flags().IntVar(&timeout, "timeout", scrape.DefaultTimeout, "Set connect timeout in seconds")
+	ac.Flags().IntVar(&readTimeout, "read-timeout", scrape.DefaultReadTimeout, "Set read timeout in seconds")
+	ac.Flags().StringVar(&proxy, "proxy", "", "Set http or socks5 proxy, e.g. socks5://127.0.0.1:1080")
+	ac.Flags().BoolVar(&insecure, "insecure", false, "Skip TLS certificate verification")
+	ac.Flags().StringVar(&profile, "profile", "", "Load site selector profile from yaml or json file")
+	ac.Flags().StringSliceVar(&mirrors, "mirror", nil, "Set equivalent mirror hosts in order of preference, e.g. www.san499.com,www.sansi03.com")
+	ac.Flags().StringVar(&rootPath, "root-path", "./data", "Set root path")
+	ac.Flags().IntVar(&concurrency, "concurrency", scrape.DefaultConcurrency, "Set number of concurrent image downloads")
+	ac.Flags().Float64Var(&rateLimit, "rate-limit", scrape.DefaultRateLimit, "Set max requests per second for each host, negative to disable")
+	ac.Flags().IntVar(&retries, "retries", scrape.DefaultRetries, "Set max retries of one request, negative to disable")
+	ac.Flags().DurationVar(&retryDelay, "retry-delay", scrape.DefaultRetryDelay, "Set base delay of exponential retry backoff")
+	ac.Flags().BoolVar(&store, "store", false, "Keep images in the shared blob store under <root-path>/"+scrape.DefaultStoreDir+" and link them into chapters")
+	ac.Flags().StringVar(&storeLink, "store-link", scrape.StoreHardlink, "Set how images link to the blob store, hardlink or reflink")
+	ac.Flags().BoolVar(&useCache, "cache", false, "Cache main and chapter pages under <root-path>/"+scrape.DefaultCacheDir)
+}
+
+func newDownloadConfig() *scrape.Config {
 	sc := new(scrape.Config)
 	sc.Debug = globalFlags.Debug
-	sc.Url = url
 	sc.Timeout = timeout
 	sc.RootPath = rootPath
+	sc.Concurrency = concurrency
+	sc.RateLimit = rateLimit
+	sc.Retries = retries
+	sc.RetryDelay = retryDelay
+	sc.ReadTimeout = readTimeout
+	sc.Proxy = proxy
+	sc.Insecure = insecure
+	sc.Profile = profile
+	sc.Mirrors = mirrors
+	sc.Cache = useCache
+	sc.Store = store
+	sc.StoreLink = storeLink
+
+	return sc
+}
+
+func scrapeCommandFunc(cmd *cobra.Command, args []string) {
+	sc := newDownloadConfig()
+	sc.Url = url
+	sc.Layout = layout
+	sc.Export = export
+	sc.VolumeSize = volumeSize
+	sc.Chapters = chapters
+
+	if fromFile != "" {
+		batchScrape(sc)
+		return
+	}
+
+	if dryRun {
+		planScrape(sc)
+		return
+	}
 
 	if err := scrape.NewWithConfig(sc).Scrape(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
+
+func batchScrape(sc *scrape.Config) {
+	if url != "" || dryRun {
+		fmt.Fprintln(os.Stderr, "--from-file can not be used with --url or --dry-run")
+		os.Exit(1)
+	}
+
+	in := os.Stdin
+	if fromFile != "-" {
+		f, err := os.Open(fromFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		defer f.Close()
+		in = f
+	}
+
+	urls, err := scrape.ReadUrlList(in)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	b := scrape.NewBatch(sc)
+	b.Jobs = jobs
+
+	results, err := b.Run(ctx, urls)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	failed, failedImages := 0, 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+
+		failedImages += result.FailedImages
+		fmt.Println(result)
+	}
+
+	fmt.Printf("total:%v, success:%v, failed:%v, failed images:%v\n", len(urls), len(results)-failed, failed, failedImages)
+	if failed > 0 || err != nil {
+		os.Exit(1)
+	}
+}
+
+func planScrape(sc *scrape.Config) {
+	p, err := scrape.NewWithConfig(sc).Plan()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if outputFormat == "json" {
+		printJson(p)
+		return
+	}
+
+	fmt.Printf("%v(%v), number:%v, layout:%v\n", p.Title, p.EnTitle, p.Number, p.Layout)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CHAPTER\tIMAGES\tON DISK\tMISSING\tDOWNLOAD SIZE\tPAGE URL")
+	for _, cp := range p.Chapters {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n", cp.Index, cp.Images, cp.OnDisk, cp.Missing, planSize(cp.DownloadSize, cp.UnknownSize), cp.PageUrl)
+	}
+
+	fmt.Fprintf(w, "TOTAL\t%v\t%v\t%v\t%v\t\n", p.Images, p.OnDisk, p.Missing, planSize(p.DownloadSize, p.UnknownSize))
+	w.Flush()
+
+	fmt.Printf("on disk: %v, to download: %v\n", humanSize(p.OnDiskSize), planSize(p.DownloadSize, p.UnknownSize))
+}
+
+func planSize(size int64, unknown int) string {
+	if unknown > 0 {
+		return fmt.Sprintf("%v+ (%v unknown)", humanSize(size), unknown)
+	}
+
+	return humanSize(size)
+}
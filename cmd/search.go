@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fengshenyun/sansi/pkg/scrape"
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchLimit int
+	reindex     bool
+)
+
+func NewSearchCommand() *cobra.Command {
+	ac := &cobra.Command{
+		Use:   "search <term> [options]",
+		Short: "Search local comics by chinese, pinyin or pinyin initials.",
+		Args:  cobra.ArbitraryArgs,
+		Run:   searchCommandFunc,
+	}
+
+	ac.Flags().StringVar(&rootPath, "root-path", "./data", "Set root path")
+	ac.Flags().IntVar(&searchLimit, "limit", scrape.DefaultSearchLimit, "Set max results, 0 means no limit")
+	ac.Flags().BoolVar(&reindex, "reindex", false, "Rebuild the search index from all comic manifests before searching")
+	ac.Flags().StringVar(&outputFormat, "output", "table", "Set output format, table or json")
+
+	return ac
+}
+
+func searchCommandFunc(cmd *cobra.Command, args []string) {
+	if reindex {
+		count, err := scrape.RebuildSearchIndex(rootPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		fmt.Fprintf(os.Stderr, "indexed %v comics\n", count)
+		if len(args) == 0 {
+			return
+		}
+	}
+
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "missing search term")
+		os.Exit(1)
+	}
+
+	l, err := scrape.OpenLibraryDB(scrape.GetLibraryDBPath(rootPath))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	defer l.Close()
+
+	results, err := l.Search(strings.Join(args, " "), searchLimit)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if outputFormat == "json" {
+		printJson(results)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SCORE\tTITLE\tPINYIN\tNUMBER\tCATEGORY\tMATCH")
+	for _, result := range results {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n", result.Score, result.Title, result.EnTitle, result.Number, result.Category, result.Match)
+	}
+
+	w.Flush()
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/fengshenyun/sansi/pkg/scrape"
+	"github.com/spf13/cobra"
+)
+
+var (
+	addr string
+)
+
+func NewServeCommand() *cobra.Command {
+	ac := &cobra.Command{
+		Use:   "serve [options]",
+		Short: "Serve downloaded comics with a local web reader.",
+		Run:   serveCommandFunc,
+	}
+
+	ac.Flags().StringVar(&rootPath, "root-path", "./data", "Set root path")
+	ac.Flags().StringVar(&addr, "addr", scrape.DefaultServeAddr, "Set listen address, e.g. :8080 to serve other devices on the network")
+
+	return ac
+}
+
+func serveCommandFunc(cmd *cobra.Command, args []string) {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           scrape.NewServer(rootPath),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Printf("serving %v on %v\n", rootPath, addr)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fengshenyun/sansi/pkg/scrape"
+	"github.com/spf13/cobra"
+)
+
+var (
+	checkOnly bool
+)
+
+func NewUpdateCommand() *cobra.Command {
+	ac := &cobra.Command{
+		Use:   "update <comic> [options]",
+		Short: "Fetch only new chapters of a downloaded comic.",
+		Long:  "Fetch only new chapters of a downloaded comic, <comic> can be the pinyin name, comic number, title or url.",
+		Args:  cobra.ExactArgs(1),
+		Run:   updateCommandFunc,
+	}
+
+	addDownloadFlags(ac)
+	ac.Flags().BoolVar(&checkOnly, "check-only", false, "Only report changes, do not download")
+	ac.Flags().StringVar(&chapters, "chapters", "", "Only check selected chapters, e.g. 1-10,45,70-")
+
+	return ac
+}
+
+func updateCommandFunc(cmd *cobra.Command, args []string) {
+	path, err := scrape.FindManifest(rootPath, args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	sc := newDownloadConfig()
+	sc.Chapters = chapters
+
+	c := scrape.NewWithConfig(sc)
+	if err = c.RestoreManifest(path); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	result, err := c.Update(!checkOnly)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Println(result)
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/fengshenyun/sansi/pkg/scrape"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchInterval time.Duration
+	watchJobs     int
+	watchOnce     bool
+	feedPath      string
+)
+
+func NewWatchCommand() *cobra.Command {
+	ac := &cobra.Command{
+		Use:   "watch [options]",
+		Short: "Poll all downloaded comics for new chapters.",
+		Run:   watchCommandFunc,
+	}
+
+	addDownloadFlags(ac)
+	ac.Flags().DurationVar(&watchInterval, "interval", scrape.DefaultWatchInterval, "Set interval between two checks")
+	ac.Flags().IntVar(&watchJobs, "jobs", scrape.DefaultWatchJobs, "Set number of comics checked at the same time")
+	ac.Flags().BoolVar(&watchOnce, "once", false, "Check once and exit")
+	ac.Flags().BoolVar(&checkOnly, "check-only", false, "Only report changes, do not download")
+	ac.Flags().StringVar(&feedPath, "feed", "", "Set feed file, default <root-path>/"+scrape.DefaultFeedName)
+
+	return ac
+}
+
+func watchCommandFunc(cmd *cobra.Command, args []string) {
+	w := scrape.NewWatcher(newDownloadConfig())
+	w.Interval = watchInterval
+	w.Jobs = watchJobs
+	w.Download = !checkOnly
+
+	if feedPath != "" {
+		w.FeedPath = feedPath
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if !watchOnce {
+		if err := w.Run(ctx); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	results, err := w.RunOnce(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	for _, result := range results {
+		fmt.Println(result)
+	}
+}
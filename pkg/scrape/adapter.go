@@ -0,0 +1,77 @@
+package scrape
+
+import (
+	"net/url"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/pkg/errors"
+)
+
+type SiteInfo struct {
+	Title          string
+	Desc           string
+	CoverUrl       string
+	LastModifyTime string
+	Category       string
+	Subtitle       string
+}
+
+type SiteAdapter interface {
+	Name() string
+	Match(u *url.URL) bool
+	ComicNumber(u *url.URL) (int, error)
+	IsValidPageUrl(mainUrl, pageUrl string) bool
+	IsValidImageUrl(imageUrl string) bool
+	ParseInfo(doc *goquery.Document) (*SiteInfo, error)
+	ParseChapters(doc *goquery.Document, mainUrl string) ([]string, error)
+	ParseImages(doc *goquery.Document) ([]*Image, error)
+}
+
+// MirrorProvider 是可选接口，站点有多个镜像域名时实现，抓取失败时会在镜像间切换
+type MirrorProvider interface {
+	Mirrors() []string
+}
+
+// ListingParser 是可选接口，支持 discover 抓取分类和标签列表页的站点才需要实现
+type ListingParser interface {
+	ParseListing(doc *goquery.Document, listUrl string) ([]*ListingEntry, string, error)
+}
+
+var (
+	adapterMu sync.RWMutex
+	adapters  []SiteAdapter
+)
+
+func RegisterAdapter(a SiteAdapter) {
+	adapterMu.Lock()
+	defer adapterMu.Unlock()
+
+	adapters = append([]SiteAdapter{a}, adapters...)
+}
+
+func Adapters() []SiteAdapter {
+	adapterMu.RLock()
+	defer adapterMu.RUnlock()
+
+	return append([]SiteAdapter{}, adapters...)
+}
+
+// FindAdapter 按 host 找站点适配器，没有匹配的适配器或 profile 时返回错误
+func FindAdapter(rawUrl string) (SiteAdapter, error) {
+	u, err := url.ParseRequestURI(rawUrl)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse url failed")
+	}
+
+	adapterMu.RLock()
+	defer adapterMu.RUnlock()
+
+	for _, a := range adapters {
+		if a.Match(u) {
+			return a, nil
+		}
+	}
+
+	return nil, errors.Errorf("unsupported site, no adapter or profile for host:%v", u.Host)
+}
@@ -0,0 +1,314 @@
+package scrape
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// 原来的 wordpress xiu 主题适配器由内置的 san499 profile 代替，选择器和规则保持一致
+func init() {
+	p, err := ParseProfile(san499Profile, false)
+	if err != nil {
+		panic(err)
+	}
+
+	a := NewProfileAdapter(p)
+	RegisterAdapter(a)
+}
+
+type profileAdapter struct {
+	profile *Profile
+	hosts   map[string]bool
+}
+
+func NewProfileAdapter(p *Profile) SiteAdapter {
+	a := &profileAdapter{profile: p, hosts: make(map[string]bool, len(p.Hosts))}
+	for _, host := range p.Hosts {
+		a.hosts[strings.ToLower(host)] = true
+	}
+
+	return a
+}
+
+func LoadProfileAdapter(path string) (SiteAdapter, error) {
+	p, err := LoadProfile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewProfileAdapter(p), nil
+}
+
+func (a *profileAdapter) Name() string {
+	return a.profile.Name
+}
+
+func (a *profileAdapter) Match(u *url.URL) bool {
+	return a.hosts[strings.ToLower(u.Hostname())]
+}
+
+func (a *profileAdapter) Mirrors() []string {
+	return a.profile.Mirrors
+}
+
+func (a *profileAdapter) ComicNumber(u *url.URL) (int, error) {
+	matches := a.profile.comicRe.FindStringSubmatch(u.Path)
+	if matches == nil {
+		return 0, errors.Errorf("url path:%v, not match comic pattern", u.Path)
+	}
+
+	num, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0, errors.Wrap(err, "invalid comic number")
+	}
+
+	return num, nil
+}
+
+func (a *profileAdapter) ParseInfo(doc *goquery.Document) (*SiteInfo, error) {
+	var (
+		info      = new(SiteInfo)
+		selectors = a.profile.Selectors
+	)
+
+	info.Title = lastText(doc, selectors.Title)
+	if info.Title == "" {
+		return nil, errors.New("no title")
+	}
+
+	info.Desc = lastText(doc, selectors.Desc)
+	info.Subtitle = lastText(doc, selectors.Subtitle)
+
+	if selectors.Cover != "" {
+		doc.Find(selectors.Cover).Each(func(i int, s *goquery.Selection) {
+			src, exist := s.Attr("src")
+			if !exist {
+				log.Info("no src attr")
+				return
+			}
+
+			info.CoverUrl = src
+		})
+	}
+
+	if info.CoverUrl == "" {
+		return nil, errors.New("no cover url")
+	}
+
+	if selectors.Meta != "" && a.profile.lastModifiedRe != nil {
+		doc.Find(selectors.Meta).Each(func(i int, s *goquery.Selection) {
+			matches := a.profile.lastModifiedRe.FindStringSubmatch(s.Text())
+			if matches == nil {
+				return
+			}
+
+			info.LastModifyTime = matches[len(matches)-1]
+		})
+	}
+
+	if selectors.Category != "" {
+		doc.Find(selectors.Category).Each(func(i int, s *goquery.Selection) {
+			if info.Category != "" {
+				return
+			}
+
+			info.Category = strings.Trim(s.Text(), " \n\t\r")
+		})
+	}
+
+	return info, nil
+}
+
+func (a *profileAdapter) ParseChapters(doc *goquery.Document, mainUrl string) ([]string, error) {
+	var (
+		existPages = make(map[string]bool, 8)
+		pageUrls   = make([]string, 0, 8)
+	)
+
+	pageUrls = append(pageUrls, mainUrl)
+	if a.profile.Selectors.Paging == "" {
+		return pageUrls, nil
+	}
+
+	doc.Find(a.profile.Selectors.Paging).Each(func(i int, s *goquery.Selection) {
+		href, exist := s.Attr("href")
+		if !exist {
+			log.Info("no href attr")
+			return
+		}
+
+		log.Infof("i:%v,href:%v", i, href)
+
+		if existPages[href] {
+			log.Infof("href:%v, already process", href)
+			return
+		}
+
+		if !a.IsValidPageUrl(mainUrl, href) {
+			log.Infof("href:%v, invalid page url", href)
+			return
+		}
+
+		pageUrls = append(pageUrls, href)
+		existPages[href] = true
+		log.Debugf("sub page url:%v", href)
+	})
+
+	return pageUrls, nil
+}
+
+func (a *profileAdapter) ParseImages(doc *goquery.Document) ([]*Image, error) {
+	existImages := make(map[string]bool, 8)
+	images := make([]*Image, 0, 8)
+
+	doc.Find(a.profile.Selectors.Images).Each(func(i int, s *goquery.Selection) {
+		src, exist := s.Attr("src")
+		if !exist {
+			log.Info("no src attr")
+			return
+		}
+
+		log.Infof("index:%v, src:%v", i, src)
+
+		if existImages[src] {
+			log.Infof("imageUrl:%v, already process", src)
+			return
+		}
+
+		if !a.IsValidImageUrl(src) {
+			log.Infof("imageUrl:%v, invalid image url", src)
+			return
+		}
+
+		image := &Image{Url: src}
+		image.Width, _ = strconv.Atoi(s.AttrOr("width", ""))
+		image.Height, _ = strconv.Atoi(s.AttrOr("height", ""))
+
+		images = append(images, image)
+		existImages[src] = true
+	})
+
+	return images, nil
+}
+
+func (a *profileAdapter) ParseListing(doc *goquery.Document, listUrl string) ([]*ListingEntry, string, error) {
+	selectors := a.profile.Selectors
+	if selectors.ListItem == "" || selectors.ListLink == "" {
+		return nil, "", errors.Errorf("profile:%v, no listing selectors", a.profile.Name)
+	}
+
+	base, err := url.Parse(listUrl)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "parse list url failed")
+	}
+
+	entries := make([]*ListingEntry, 0, 16)
+	doc.Find(selectors.ListItem).Each(func(i int, s *goquery.Selection) {
+		link := s.Find(selectors.ListLink).First()
+
+		href, exist := link.Attr("href")
+		if !exist {
+			log.Info("no href attr")
+			return
+		}
+
+		u, err := base.Parse(href)
+		if err != nil {
+			log.Infof("href:%v, parse failed, err:%v", href, err)
+			return
+		}
+
+		num, err := a.ComicNumber(u)
+		if err != nil {
+			log.Infof("href:%v, not a comic url", href)
+			return
+		}
+
+		entry := &ListingEntry{Url: u.String(), Number: num}
+		entry.Title = strings.TrimSpace(link.AttrOr("title", ""))
+		if entry.Title == "" {
+			entry.Title = strings.Trim(link.Text(), " \n\t\r")
+		}
+
+		if selectors.ListCover != "" {
+			img := s.Find(selectors.ListCover).First()
+			// 主题开启懒加载时真实地址在 data-src
+			cover := img.AttrOr("data-src", img.AttrOr("src", ""))
+			if cover != "" {
+				if cu, err := base.Parse(cover); err == nil {
+					entry.CoverUrl = cu.String()
+				}
+			}
+		}
+
+		entries = append(entries, entry)
+	})
+
+	var next string
+	if selectors.ListNext != "" {
+		if href, exist := doc.Find(selectors.ListNext).First().Attr("href"); exist {
+			if u, err := base.Parse(href); err == nil {
+				next = u.String()
+			}
+		}
+	}
+
+	return entries, next, nil
+}
+
+func (a *profileAdapter) IsValidPageUrl(mainUrl, pageUrl string) bool {
+	if pageUrl == mainUrl {
+		return true
+	}
+
+	u, err := url.ParseRequestURI(pageUrl)
+	if err != nil {
+		log.Errorf("pageUrl:%v parse failed, err:%v", pageUrl, err)
+		return false
+	}
+
+	if a.profile.pageRe != nil && !a.profile.pageRe.MatchString(u.Path) {
+		log.Errorf("pageUrl:%v, not match page pattern", pageUrl)
+		return false
+	}
+
+	log.Debugf("pageUrl:%v verify success", pageUrl)
+	return true
+}
+
+func (a *profileAdapter) IsValidImageUrl(imageUrl string) bool {
+	if a.profile.imageRe == nil {
+		return isValidImageSuffix(imageUrl)
+	}
+
+	u, err := url.ParseRequestURI(imageUrl)
+	if err != nil {
+		log.Errorf("imageUrl:%v parse failed, err:%v", imageUrl, err)
+		return false
+	}
+
+	if !a.profile.imageRe.MatchString(u.Path) {
+		log.Errorf("imageUrl:%v, not match image pattern", imageUrl)
+		return false
+	}
+
+	return true
+}
+
+func lastText(doc *goquery.Document, selector string) string {
+	if selector == "" {
+		return ""
+	}
+
+	text := ""
+	doc.Find(selector).Each(func(i int, s *goquery.Selection) {
+		text = strings.Trim(s.Text(), " \n\t\r")
+	})
+
+	return text
+}
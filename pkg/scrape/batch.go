@@ -0,0 +1,176 @@
+package scrape
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultBatchJobs = 2
+)
+
+type BatchResult struct {
+	Url          string        `json:"url"`
+	Title        string        `json:"title"`
+	EnTitle      string        `json:"pinyin_title"`
+	Number       int           `json:"number"`
+	Chapters     int           `json:"chapters"`
+	Images       int           `json:"images"`
+	FailedPages  int           `json:"failed_pages"`
+	FailedImages int           `json:"failed_images"`
+	Duration     time.Duration `json:"duration"`
+	Error        string        `json:"error,omitempty"`
+}
+
+func (r *BatchResult) String() string {
+	name := r.Title
+	if name == "" {
+		name = r.Url
+	}
+
+	if r.Error != "" {
+		return fmt.Sprintf("FAIL %v, %v chapters, %v images, %v failed images, err:%v", name, r.Chapters, r.Images, r.FailedImages, r.Error)
+	}
+
+	return fmt.Sprintf("OK   %v, %v chapters, %v images, %v", name, r.Chapters, r.Images, r.Duration.Round(time.Second))
+}
+
+// Batch 在一个进程里抓取多本漫画，所有漫画共用一个 http client 和限速器
+type Batch struct {
+	Config *Config
+	Jobs   int
+
+	client  *http.Client
+	limiter *hostLimiter
+}
+
+func NewBatch(cfg *Config) *Batch {
+	return &Batch{
+		Config: cfg,
+		Jobs:   DefaultBatchJobs,
+	}
+}
+
+func (b *Batch) Run(ctx context.Context, urls []string) ([]*BatchResult, error) {
+	if b.client == nil {
+		client, err := NewHttpClient(b.Config.ClientOptions())
+		if err != nil {
+			return nil, errors.Wrap(err, "create http client failed")
+		}
+
+		b.client = client
+	}
+
+	if b.limiter == nil {
+		rate := b.Config.RateLimit
+		if rate == 0 {
+			rate = DefaultRateLimit
+		}
+
+		b.limiter = newHostLimiter(rate)
+	}
+
+	jobs := b.Jobs
+	if jobs <= 0 {
+		jobs = 1
+	}
+
+	var (
+		wg      sync.WaitGroup
+		sem     = make(chan struct{}, jobs)
+		results = make([]*BatchResult, len(urls))
+	)
+
+	for i, rawUrl := range urls {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return results[:i], ctx.Err()
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(i int, rawUrl string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			results[i] = b.scrape(ctx, rawUrl)
+			log.Infof("batch, %v", results[i])
+		}(i, rawUrl)
+	}
+
+	wg.Wait()
+	return results, nil
+}
+
+func (b *Batch) scrape(ctx context.Context, rawUrl string) *BatchResult {
+	cfg := *b.Config
+	cfg.Url = rawUrl
+
+	c := NewWithConfig(&cfg)
+	c.SetHttpClient(b.client)
+	c.SetContext(ctx)
+	c.limiter = b.limiter
+
+	start := time.Now()
+	err := c.Scrape()
+
+	result := &BatchResult{
+		Url:          c.MainUrl,
+		Title:        c.Title,
+		EnTitle:      c.EnTitle,
+		Number:       c.Number,
+		Chapters:     len(c.Chapters),
+		Images:       len(c.ImageUrls),
+		FailedPages:  c.FailedPages(),
+		FailedImages: c.FailedImages(),
+		Duration:     time.Since(start),
+	}
+
+	// 图片和章节页失败时 Scrape 仍返回 nil，按下载结果判断成功与否
+	switch {
+	case err != nil:
+		result.Error = err.Error()
+	case result.FailedPages > 0 || result.FailedImages > 0:
+		result.Error = fmt.Sprintf("%v of %v chapter pages and %v of %v images failed",
+			result.FailedPages, result.Chapters, result.FailedImages, result.Images)
+	}
+
+	return result
+}
+
+// ReadUrlList 每行一个地址，忽略空行、# 开头的注释和重复地址
+func ReadUrlList(r io.Reader) ([]string, error) {
+	var (
+		urls    = make([]string, 0, 16)
+		exist   = make(map[string]bool, 16)
+		scanner = bufio.NewScanner(r)
+	)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || exist[line] {
+			continue
+		}
+
+		urls = append(urls, line)
+		exist[line] = true
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "read url list failed")
+	}
+
+	return urls, nil
+}
@@ -1,5 +1,406 @@
 package scrape
 
-func InitCache() {
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultCacheDir        = ".cache"
+	DefaultMainPageTTL     = 10 * time.Minute
+	DefaultChapterPageTTL  = 24 * time.Hour
+	cacheMetaSuffix        = ".json"
+	cacheBodySuffix        = ".body"
+	cacheStatusHeader      = "X-Sansi-Cache"
+	cacheStatusHit         = "hit"
+	cacheStatusRevalidated = "revalidated"
+)
+
+type CacheEntry struct {
+	Url          string        `json:"url"`
+	ETag         string        `json:"etag,omitempty"`
+	LastModified string        `json:"last_modified,omitempty"`
+	ContentType  string        `json:"content_type,omitempty"`
+	Size         int64         `json:"size"`
+	TTL          time.Duration `json:"ttl"`
+	StoredAt     time.Time     `json:"stored_at"`
+}
+
+func (e *CacheEntry) Fresh(ttl time.Duration) bool {
+	return ttl < 0 || time.Since(e.StoredAt) < ttl
+}
+
+func (e *CacheEntry) Expired() bool {
+	return !e.Fresh(e.TTL)
+}
+
+type CacheStats struct {
+	Dir     string `json:"dir"`
+	Entries int    `json:"entries"`
+	Expired int    `json:"expired"`
+	Size    int64  `json:"size"`
+}
+
+// HttpCache 按 url 缓存 GET 响应，元数据和响应体分开存放：
+// <dir>/<sha256 前两位>/<sha256>.json 和 .body
+type HttpCache struct {
+	Dir string
+}
+
+func InitCache(dir string) (*HttpCache, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, errors.Wrap(err, "create cache dir failed")
+	}
+
+	return &HttpCache{Dir: dir}, nil
+}
+
+func GetCacheDir(rootPath string) string {
+	return filepath.Join(rootPath, DefaultCacheDir)
+}
+
+// WithCacheTTL 标记请求可以走缓存，ttl 小于 0 时永不过期；没有标记的请求不走缓存。
+// 图片不走这里，下载好的图片文件加上 journal 里的校验和就是它的永久缓存
+func WithCacheTTL(ctx context.Context, ttl time.Duration) context.Context {
+	return context.WithValue(ctx, cacheTTLKey{}, ttl)
+}
+
+type cacheTTLKey struct{}
+
+func cacheTTL(ctx context.Context) (time.Duration, bool) {
+	ttl, ok := ctx.Value(cacheTTLKey{}).(time.Duration)
+	return ttl, ok
+}
+
+func (h *HttpCache) path(rawUrl string) string {
+	sum := sha256.Sum256([]byte(rawUrl))
+	key := hex.EncodeToString(sum[:])
+
+	return filepath.Join(h.Dir, key[:2], key)
+}
+
+func (h *HttpCache) Get(rawUrl string) (*CacheEntry, error) {
+	data, err := os.ReadFile(h.path(rawUrl) + cacheMetaSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := new(CacheEntry)
+	if err = json.Unmarshal(data, entry); err != nil {
+		return nil, errors.Wrap(err, "unmarshal cache entry failed")
+	}
+
+	if entry.Url != rawUrl {
+		return nil, errors.Errorf("cache entry url mismatch:%v", entry.Url)
+	}
+
+	stat, err := os.Stat(h.path(rawUrl) + cacheBodySuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	if stat.Size() != entry.Size {
+		return nil, errors.Errorf("cache body size mismatch, expected:%v, received:%v", entry.Size, stat.Size())
+	}
+
+	return entry, nil
+}
+
+func (h *HttpCache) putMeta(entry *CacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(h.path(entry.Url)+cacheMetaSuffix, data)
+}
+
+func (h *HttpCache) response(entry *CacheEntry, req *http.Request, status string) (*http.Response, error) {
+	fd, err := os.Open(h.path(entry.Url) + cacheBodySuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make(http.Header)
+	header.Set("Content-Length", strconv.FormatInt(entry.Size, 10))
+	header.Set(cacheStatusHeader, status)
+	if entry.ContentType != "" {
+		header.Set("Content-Type", entry.ContentType)
+	}
+
+	if entry.ETag != "" {
+		header.Set("ETag", entry.ETag)
+	}
+
+	if entry.LastModified != "" {
+		header.Set("Last-Modified", entry.LastModified)
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          fd,
+		ContentLength: entry.Size,
+		Request:       req,
+	}, nil
+}
+
+func (h *HttpCache) walk(fn func(metaPath string, entry *CacheEntry)) error {
+	err := filepath.Walk(h.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			return err
+		}
+
+		if info.IsDir() || !strings.HasSuffix(path, cacheMetaSuffix) {
+			return nil
+		}
+
+		entry := new(CacheEntry)
+		if data, err := os.ReadFile(path); err == nil {
+			if err = json.Unmarshal(data, entry); err != nil {
+				log.Errorf("cache meta:%v, unmarshal failed, err:%v", path, err)
+			}
+		}
+
+		fn(path, entry)
+		return nil
+	})
+
+	return errors.Wrap(err, "walk cache dir failed")
+}
+
+func (h *HttpCache) Stats() (*CacheStats, error) {
+	stats := &CacheStats{Dir: h.Dir}
+
+	err := h.walk(func(metaPath string, entry *CacheEntry) {
+		stats.Entries++
+		stats.Size += entry.Size
+		if entry.Expired() {
+			stats.Expired++
+		}
+	})
+
+	return stats, err
+}
+
+// Clear 删除缓存，expiredOnly 为 true 时只删除已过期的，返回删除的条目数和大小
+func (h *HttpCache) Clear(expiredOnly bool) (int, int64, error) {
+	var (
+		count int
+		size  int64
+	)
+
+	if !expiredOnly {
+		stats, err := h.Stats()
+		if err != nil {
+			return 0, 0, err
+		}
+
+		if err = os.RemoveAll(h.Dir); err != nil {
+			return 0, 0, errors.Wrap(err, "remove cache dir failed")
+		}
+
+		return stats.Entries, stats.Size, nil
+	}
+
+	err := h.walk(func(metaPath string, entry *CacheEntry) {
+		if !entry.Expired() {
+			return
+		}
+
+		bodyPath := strings.TrimSuffix(metaPath, cacheMetaSuffix) + cacheBodySuffix
+		if err := os.Remove(metaPath); err != nil {
+			log.Errorf("cache meta:%v, remove failed, err:%v", metaPath, err)
+			return
+		}
+
+		_ = os.Remove(bodyPath)
+		count++
+		size += entry.Size
+	})
+
+	return count, size, err
+}
+
+type cacheTransport struct {
+	base  http.RoundTripper
+	cache *HttpCache
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ttl, ok := cacheTTL(req.Context())
+	if !ok || req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+
+	rawUrl := req.URL.String()
+
+	entry, err := t.cache.Get(rawUrl)
+	if err != nil && !os.IsNotExist(err) {
+		log.Infof("url:%v, ignore broken cache entry, err:%v", rawUrl, err)
+		entry = nil
+	}
+
+	if entry != nil && entry.Fresh(ttl) {
+		if resp, err := t.cache.response(entry, req, cacheStatusHit); err == nil {
+			log.Debugf("url:%v, cache hit", rawUrl)
+			return resp, nil
+		}
+	}
+
+	if entry != nil {
+		req = req.Clone(req.Context())
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		resp.Body.Close()
+
+		entry.TTL = ttl
+		entry.StoredAt = time.Now()
+		if err = t.cache.putMeta(entry); err != nil {
+			log.Errorf("url:%v, update cache entry failed, err:%v", rawUrl, err)
+		}
+
+		log.Debugf("url:%v, not modified, use cache", rawUrl)
+		return t.cache.response(entry, req, cacheStatusRevalidated)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	bodyPath := t.cache.path(rawUrl) + cacheBodySuffix
+	fd, err := createTempFile(bodyPath)
+	if err != nil {
+		log.Errorf("url:%v, create cache file failed, err:%v", rawUrl, err)
+		return resp, nil
+	}
+
+	resp.Body = &cacheBody{
+		ReadCloser: resp.Body,
+		fd:         fd,
+		path:       bodyPath,
+		cache:      t.cache,
+		expected:   resp.ContentLength,
+		entry: &CacheEntry{
+			Url:          rawUrl,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			ContentType:  resp.Header.Get("Content-Type"),
+			TTL:          ttl,
+		},
+	}
+
+	return resp, nil
+}
+
+// cacheBody 边读边写缓存文件，只有完整读到 EOF 才落盘
+type cacheBody struct {
+	io.ReadCloser
+	fd       *os.File
+	path     string
+	cache    *HttpCache
+	entry    *CacheEntry
+	expected int64
+	written  int64
+	failed   bool
+	done     bool
+}
+
+func (b *cacheBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 && !b.failed {
+		if _, werr := b.fd.Write(p[:n]); werr != nil {
+			log.Errorf("url:%v, write cache file failed, err:%v", b.entry.Url, werr)
+			b.failed = true
+		}
+
+		b.written += int64(n)
+	}
+
+	if err == io.EOF {
+		b.commit()
+	}
+
+	return n, err
+}
+
+func (b *cacheBody) Close() error {
+	b.discard()
+	return b.ReadCloser.Close()
+}
+
+func (b *cacheBody) commit() {
+	if b.done {
+		return
+	}
+
+	b.done = true
+	if b.failed || (b.expected >= 0 && b.written != b.expected) {
+		b.cleanup()
+		return
+	}
+
+	if err := b.fd.Close(); err != nil {
+		_ = os.Remove(b.fd.Name())
+		return
+	}
+
+	if err := os.Rename(b.fd.Name(), b.path); err != nil {
+		log.Errorf("url:%v, rename cache file failed, err:%v", b.entry.Url, err)
+		_ = os.Remove(b.fd.Name())
+		return
+	}
+
+	b.entry.Size = b.written
+	b.entry.StoredAt = time.Now()
+	if err := b.cache.putMeta(b.entry); err != nil {
+		log.Errorf("url:%v, write cache entry failed, err:%v", b.entry.Url, err)
+	}
+}
+
+func (b *cacheBody) discard() {
+	if b.done {
+		return
+	}
+
+	b.done = true
+	b.cleanup()
+}
+
+func (b *cacheBody) cleanup() {
+	b.fd.Close()
+	_ = os.Remove(b.fd.Name())
 }
@@ -0,0 +1,131 @@
+package scrape
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheTransport(t *testing.T) {
+	const body = "<html>chapter page</html>"
+
+	var (
+		hits        int32
+		conditional int32
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+
+		switch r.URL.Path {
+		case "/page.html":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				atomic.AddInt32(&conditional, 1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Content-Type", "text/html")
+			io.WriteString(w, body)
+		case "/truncated.html":
+			// 声明的长度比实际多，连接提前断开
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)*2))
+			io.WriteString(w, body)
+		case "/missing.html":
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cache, err := InitCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &http.Client{Transport: &cacheTransport{base: http.DefaultTransport, cache: cache}}
+
+	get := func(ctx context.Context, path string, readAll bool) (*http.Response, string, error) {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+path, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, "", err
+		}
+
+		defer resp.Body.Close()
+
+		if !readAll {
+			buf := make([]byte, 4)
+			_, err = io.ReadFull(resp.Body, buf)
+			return resp, string(buf), err
+		}
+
+		data, err := io.ReadAll(resp.Body)
+		return resp, string(data), err
+	}
+
+	cached := WithCacheTTL(context.Background(), time.Hour)
+	stale := WithCacheTTL(context.Background(), 0)
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		path        string
+		readAll     bool
+		wantErr     bool
+		wantStatus  string
+		wantHits    int32
+		wantCached  bool
+		wantRevalid int32
+	}{
+		// 没有标记 ttl 的请求不走缓存
+		{"no ttl", context.Background(), "/page.html", true, false, "", 1, false, 0},
+		// 只读了一部分就关闭，不落盘
+		{"partial read", cached, "/page.html", false, false, "", 2, false, 0},
+		{"miss", cached, "/page.html", true, false, "", 3, true, 0},
+		{"hit", cached, "/page.html", true, false, cacheStatusHit, 3, true, 0},
+		{"revalidated", stale, "/page.html", true, false, cacheStatusRevalidated, 4, true, 1},
+		{"truncated body", cached, "/truncated.html", true, true, "", 5, false, 1},
+		{"not found", cached, "/missing.html", true, false, "", 6, false, 1},
+	}
+
+	for _, tt := range tests {
+		resp, data, err := get(tt.ctx, tt.path, tt.readAll)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%v: err = nil, want error", tt.name)
+			}
+		} else if err != nil {
+			t.Fatalf("%v: err = %v", tt.name, err)
+		} else if tt.readAll && resp.StatusCode == http.StatusOK && data != body {
+			t.Errorf("%v: body = %q, want %q", tt.name, data, body)
+		}
+
+		if resp != nil && resp.Header.Get(cacheStatusHeader) != tt.wantStatus {
+			t.Errorf("%v: cache status = %q, want %q", tt.name, resp.Header.Get(cacheStatusHeader), tt.wantStatus)
+		}
+
+		if got := atomic.LoadInt32(&hits); got != tt.wantHits {
+			t.Errorf("%v: server hits = %v, want %v", tt.name, got, tt.wantHits)
+		}
+
+		if got := atomic.LoadInt32(&conditional); got != tt.wantRevalid {
+			t.Errorf("%v: conditional requests = %v, want %v", tt.name, got, tt.wantRevalid)
+		}
+
+		entry, err := cache.Get(srv.URL + tt.path)
+		if tt.wantCached != (err == nil) {
+			t.Errorf("%v: cache entry = %v, err = %v, want cached %v", tt.name, entry, err, tt.wantCached)
+		}
+
+		if !tt.wantCached && err != nil && !os.IsNotExist(err) {
+			t.Errorf("%v: cache entry err = %v, want not exist", tt.name, err)
+		}
+	}
+}
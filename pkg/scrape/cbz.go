@@ -0,0 +1,157 @@
+package scrape
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type ComicInfo struct {
+	XMLName     xml.Name        `xml:"ComicInfo"`
+	XmlnsXsi    string          `xml:"xmlns:xsi,attr"`
+	XmlnsXsd    string          `xml:"xmlns:xsd,attr"`
+	Title       string          `xml:"Title,omitempty"`
+	Series      string          `xml:"Series,omitempty"`
+	Number      string          `xml:"Number,omitempty"`
+	Count       int             `xml:"Count,omitempty"`
+	Summary     string          `xml:"Summary,omitempty"`
+	Year        int             `xml:"Year,omitempty"`
+	Month       int             `xml:"Month,omitempty"`
+	Day         int             `xml:"Day,omitempty"`
+	Genre       string          `xml:"Genre,omitempty"`
+	Web         string          `xml:"Web,omitempty"`
+	PageCount   int             `xml:"PageCount"`
+	LanguageISO string          `xml:"LanguageISO,omitempty"`
+	Pages       []ComicPageInfo `xml:"Pages>Page"`
+}
+
+type ComicPageInfo struct {
+	Image int    `xml:"Image,attr"`
+	Type  string `xml:"Type,attr,omitempty"`
+}
+
+func (c *Comics) exportCbz() error {
+	failed := make([]string, 0, 4)
+	for _, chapter := range c.Chapters {
+		cbzPath := c.getExportDataPath(fmt.Sprintf("%s-%s.cbz", c.EnTitle, chapter.Name()))
+
+		if err := c.writeCbz(cbzPath, chapter); err != nil {
+			log.Errorf("chapter:%v, write cbz failed, err:%v", chapter.Index, err)
+			failed = append(failed, fmt.Sprintf("chapter %v: %v", chapter.Index, err))
+			continue
+		}
+
+		log.Debugf("chapter:%v, write cbz:%v success", chapter.Index, cbzPath)
+	}
+
+	return exportFailed(ExportCbz, len(c.Chapters), failed)
+}
+
+// writeCbz 先写临时文件，全部成功后才改名，失败时不会留下半截的 cbz
+func (c *Comics) writeCbz(cbzPath string, chapter *Chapter) error {
+	images, err := c.getExportImages(chapter)
+	if err != nil {
+		return err
+	}
+
+	fd, err := createTempFile(cbzPath)
+	if err != nil {
+		return err
+	}
+
+	return commitTempFile(fd, cbzPath, func(w io.Writer) error {
+		return c.writeCbzArchive(w, chapter, images)
+	})
+}
+
+func (c *Comics) writeCbzArchive(fd io.Writer, chapter *Chapter, images []*exportImage) (err error) {
+	zw := zip.NewWriter(fd)
+	info := c.newComicInfo(chapter)
+
+	if coverPath, ok := c.getExportCover(); ok {
+		name := fmt.Sprintf("%03d%s", 0, filepath.Ext(coverPath))
+		if err = addZipFile(zw, name, coverPath); err != nil {
+			return errors.Wrap(err, "add cover failed")
+		}
+
+		info.Pages = append(info.Pages, ComicPageInfo{Image: len(info.Pages), Type: "FrontCover"})
+	}
+
+	for _, image := range images {
+		name := fmt.Sprintf("%03d%s", image.Index, image.Ext())
+		if err = addZipFile(zw, name, image.Path); err != nil {
+			return errors.Wrapf(err, "add image:%v failed", image.Url)
+		}
+
+		info.Pages = append(info.Pages, ComicPageInfo{Image: len(info.Pages)})
+	}
+
+	info.PageCount = len(info.Pages)
+
+	w, err := zw.Create("ComicInfo.xml")
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err = enc.Encode(info); err != nil {
+		return errors.Wrap(err, "encode ComicInfo.xml failed")
+	}
+
+	return zw.Close()
+}
+
+func (c *Comics) newComicInfo(chapter *Chapter) *ComicInfo {
+	info := &ComicInfo{
+		XmlnsXsi:    "http://www.w3.org/2001/XMLSchema-instance",
+		XmlnsXsd:    "http://www.w3.org/2001/XMLSchema",
+		Title:       fmt.Sprintf("第%d话", chapter.Index),
+		Series:      c.Title,
+		Number:      strconv.Itoa(chapter.Index),
+		Count:       len(c.PageUrls),
+		Summary:     c.Desc,
+		Genre:       c.Category,
+		Web:         c.MainUrl,
+		LanguageISO: "zh",
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", c.LastModifyTime, time.Local); err == nil {
+		info.Year, info.Month, info.Day = t.Year(), int(t.Month()), t.Day()
+	}
+
+	return info
+}
+
+func addZipFile(zw *zip.Writer, name, path string) error {
+	fd, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer fd.Close()
+
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Store,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, fd)
+	return err
+}
@@ -0,0 +1,121 @@
+package scrape
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	LayoutChapter = "chapter"
+	LayoutCdn     = "cdn"
+)
+
+type Chapter struct {
+	Index   int      `json:"index"`
+	PageUrl string   `json:"page_url"`
+	Images  []*Image `json:"images"`
+}
+
+type Image struct {
+	Url     string `json:"url"`
+	Chapter int    `json:"-"`
+	Index   int    `json:"index"`
+	Width   int    `json:"width,omitempty"`
+	Height  int    `json:"height,omitempty"`
+}
+
+func (c *Chapter) Name() string {
+	return chapterName(c.Index)
+}
+
+func chapterName(index int) string {
+	return fmt.Sprintf("%04d", index)
+}
+
+func (i *Image) Name() string {
+	suffix := "jpg"
+	if u, err := url.ParseRequestURI(i.Url); err == nil {
+		if ext := strings.TrimPrefix(path.Ext(u.Path), "."); ext != "" {
+			suffix = strings.ToLower(ext)
+		}
+	}
+
+	return fmt.Sprintf("%03d.%s", i.Index, suffix)
+}
+
+func IsValidLayout(layout string) bool {
+	return layout == LayoutChapter || layout == LayoutCdn
+}
+
+type chapterSpan struct {
+	from int
+	to   int // 0 表示到最后一话
+}
+
+// ChapterRange 章节选择，如 1-10,45,70-，章节序号从 1 开始
+type ChapterRange []chapterSpan
+
+func ParseChapterRange(s string) (ChapterRange, error) {
+	var r ChapterRange
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		var (
+			span     chapterSpan
+			err      error
+			from, to = part, part
+		)
+
+		if i := strings.Index(part, "-"); i >= 0 {
+			from, to = part[:i], part[i+1:]
+		}
+
+		if from == "" {
+			span.from = 1
+		} else if span.from, err = strconv.Atoi(strings.TrimSpace(from)); err != nil {
+			return nil, errors.Errorf("invalid chapter range:%v", part)
+		}
+
+		// 没写结束章节才表示到最后一话，写了就不能小于开始章节，5-0 也是错的
+		if to != "" {
+			if span.to, err = strconv.Atoi(strings.TrimSpace(to)); err != nil || span.to < span.from {
+				return nil, errors.Errorf("invalid chapter range:%v", part)
+			}
+		}
+
+		if span.from <= 0 {
+			return nil, errors.Errorf("invalid chapter range:%v", part)
+		}
+
+		r = append(r, span)
+	}
+
+	if len(r) == 0 {
+		return nil, errors.Errorf("empty chapter range:%v", s)
+	}
+
+	return r, nil
+}
+
+func (r ChapterRange) Contains(index int) bool {
+	if len(r) == 0 {
+		return true
+	}
+
+	for _, span := range r {
+		if index >= span.from && (span.to == 0 || index <= span.to) {
+			return true
+		}
+	}
+
+	return false
+}
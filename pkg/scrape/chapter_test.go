@@ -0,0 +1,57 @@
+package scrape
+
+import (
+	"testing"
+)
+
+func TestParseChapterRange(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantErr  bool
+		contains []int
+		excludes []int
+	}{
+		{"1-10,45,70-", false, []int{1, 10, 45, 70, 999}, []int{11, 44, 46, 69}},
+		{"5", false, []int{5}, []int{4, 6}},
+		{"-3", false, []int{1, 3}, []int{4}},
+		{"3-", false, []int{3, 100}, []int{2}},
+		{" 2 - 4 , ", false, []int{2, 4}, []int{1, 5}},
+		{"5-5", false, []int{5}, []int{4, 6}},
+		{"5-0", true, nil, nil},
+		{"5-3", true, nil, nil},
+		{"0", true, nil, nil},
+		{"0-3", true, nil, nil},
+		{"-1-3", true, nil, nil},
+		{"a-3", true, nil, nil},
+		{"3-b", true, nil, nil},
+		{"", true, nil, nil},
+		{",", true, nil, nil},
+	}
+
+	for _, tt := range tests {
+		r, err := ParseChapterRange(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseChapterRange(%q) = %v, want error", tt.in, r)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("ParseChapterRange(%q) err = %v", tt.in, err)
+			continue
+		}
+
+		for _, index := range tt.contains {
+			if !r.Contains(index) {
+				t.Errorf("ParseChapterRange(%q).Contains(%v) = false, want true", tt.in, index)
+			}
+		}
+
+		for _, index := range tt.excludes {
+			if r.Contains(index) {
+				t.Errorf("ParseChapterRange(%q).Contains(%v) = true, want false", tt.in, index)
+			}
+		}
+	}
+}
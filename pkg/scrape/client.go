@@ -0,0 +1,102 @@
+package scrape
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultReadTimeout = 30 // 秒
+)
+
+type ClientOptions struct {
+	Timeout     int
+	ReadTimeout int
+	Proxy       string
+	Insecure    bool
+	CacheDir    string
+}
+
+func NewHttpClient(opt ClientOptions) (*http.Client, error) {
+	connectTimeout := time.Duration(opt.Timeout) * time.Second
+	if connectTimeout <= 0 {
+		connectTimeout = DefaultTimeout * time.Second
+	}
+
+	readTimeout := time.Duration(opt.ReadTimeout) * time.Second
+	if readTimeout <= 0 {
+		readTimeout = DefaultReadTimeout * time.Second
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if opt.Proxy != "" {
+		u, err := url.Parse(opt.Proxy)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse proxy url failed")
+		}
+
+		if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5" && u.Scheme != "socks5h" {
+			return nil, errors.Errorf("unsupported proxy scheme:%v", u.Scheme)
+		}
+
+		proxy = http.ProxyURL(u)
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+
+			return &readTimeoutConn{Conn: conn, timeout: readTimeout}, nil
+		},
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: opt.Insecure,
+		},
+		TLSHandshakeTimeout:   connectTimeout,
+		ResponseHeaderTimeout: readTimeout,
+		ExpectContinueTimeout: time.Second,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   16,
+		IdleConnTimeout:       90 * time.Second,
+		ForceAttemptHTTP2:     true,
+	}
+
+	base := &limitTransport{base: transport}
+	if opt.CacheDir == "" {
+		return &http.Client{Transport: base}, nil
+	}
+
+	cache, err := InitCache(opt.CacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{Transport: &cacheTransport{base: base, cache: cache}}, nil
+}
+
+type readTimeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *readTimeoutConn) Read(b []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+
+	return c.Conn.Read(b)
+}
@@ -1,8 +1,43 @@
 package scrape
 
+import (
+	"time"
+)
+
 type Config struct {
-	Debug    bool
-	Url      string
-	Timeout  int
-	RootPath string
+	Debug       bool
+	Url         string
+	Timeout     int
+	RootPath    string
+	Concurrency int
+	RateLimit   float64
+	Layout      string
+	Export      string
+	VolumeSize  int
+	Retries     int
+	RetryDelay  time.Duration
+	ReadTimeout int
+	Proxy       string
+	Insecure    bool
+	Profile     string
+	Mirrors     []string
+	Chapters    string
+	Cache       bool
+	Store       bool
+	StoreLink   string
+}
+
+func (cfg *Config) ClientOptions() ClientOptions {
+	opt := ClientOptions{
+		Timeout:     cfg.Timeout,
+		ReadTimeout: cfg.ReadTimeout,
+		Proxy:       cfg.Proxy,
+		Insecure:    cfg.Insecure,
+	}
+
+	if cfg.Cache && cfg.RootPath != "" {
+		opt.CacheDir = GetCacheDir(cfg.RootPath)
+	}
+
+	return opt
 }
@@ -6,3 +6,6 @@ import (
 
 //go:embed 101344455.html
 var badMan []byte
+
+//go:embed profiles/san499.yaml
+var san499Profile []byte
@@ -0,0 +1,317 @@
+package scrape
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	DefaultLibraryDBName = "library.db"
+)
+
+const librarySchema = `
+CREATE TABLE IF NOT EXISTS comics (
+	number        INTEGER PRIMARY KEY,
+	title         TEXT NOT NULL,
+	pinyin_title  TEXT NOT NULL,
+	url           TEXT NOT NULL,
+	category      TEXT,
+	subtitle      TEXT,
+	description   TEXT,
+	cover_url     TEXT,
+	last_modified TEXT,
+	layout        TEXT,
+	chapters      INTEGER NOT NULL DEFAULT 0,
+	images        INTEGER NOT NULL DEFAULT 0,
+	size          INTEGER NOT NULL DEFAULT 0,
+	scraped_at    TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS chapters (
+	comic_number INTEGER NOT NULL,
+	idx          INTEGER NOT NULL,
+	page_url     TEXT NOT NULL,
+	images       INTEGER NOT NULL DEFAULT 0,
+	status       TEXT NOT NULL,
+	PRIMARY KEY (comic_number, idx)
+);
+
+CREATE TABLE IF NOT EXISTS images (
+	comic_number INTEGER NOT NULL,
+	chapter      INTEGER NOT NULL,
+	idx          INTEGER NOT NULL,
+	url          TEXT NOT NULL,
+	path         TEXT,
+	status       TEXT NOT NULL,
+	size         INTEGER NOT NULL DEFAULT 0,
+	sha256       TEXT,
+	error        TEXT,
+	width        INTEGER,
+	height       INTEGER,
+	updated_at   TEXT,
+	PRIMARY KEY (comic_number, chapter, idx)
+);
+
+CREATE INDEX IF NOT EXISTS images_status ON images (status);
+
+CREATE TABLE IF NOT EXISTS search_index (
+	number       INTEGER PRIMARY KEY,
+	title        TEXT NOT NULL,
+	pinyin_title TEXT NOT NULL,
+	initials     TEXT NOT NULL,
+	category     TEXT,
+	subtitle     TEXT,
+	description  TEXT,
+	updated_at   TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS scrapes (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	comic_number INTEGER NOT NULL,
+	started_at   TEXT NOT NULL,
+	finished_at  TEXT NOT NULL,
+	chapters     INTEGER NOT NULL DEFAULT 0,
+	images       INTEGER NOT NULL DEFAULT 0,
+	failed       INTEGER NOT NULL DEFAULT 0,
+	error        TEXT
+);
+`
+
+// LibraryReports 是 query 命令内置的几个常用查询
+var LibraryReports = map[string]string{
+	"updated": `SELECT number, title, last_modified, chapters FROM comics
+		WHERE last_modified >= date('now', 'start of month') ORDER BY last_modified DESC`,
+	"failed": `SELECT c.title, i.chapter, i.idx, i.url, i.error FROM images i JOIN comics c ON c.number = i.comic_number
+		WHERE i.status = 'failed' ORDER BY c.title, i.chapter, i.idx`,
+	"incomplete": `SELECT c.title, ch.idx AS chapter, ch.images, SUM(i.status = 'done') AS done FROM chapters ch
+		JOIN comics c ON c.number = ch.comic_number
+		LEFT JOIN images i ON i.comic_number = ch.comic_number AND i.chapter = ch.idx
+		GROUP BY ch.comic_number, ch.idx HAVING done < ch.images OR ch.images = 0 ORDER BY c.title, ch.idx`,
+	"sizes": `SELECT number, title, chapters, images, size FROM comics ORDER BY size DESC`,
+	"scrapes": `SELECT s.id, c.title, s.started_at, s.finished_at, s.images, s.failed, s.error FROM scrapes s
+		LEFT JOIN comics c ON c.number = s.comic_number ORDER BY s.id DESC LIMIT 50`,
+}
+
+func ReportNames() []string {
+	names := make([]string, 0, len(LibraryReports))
+	for name := range LibraryReports {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+type LibraryDB struct {
+	db *sql.DB
+}
+
+func GetLibraryDBPath(rootPath string) string {
+	return filepath.Join(rootPath, DefaultLibraryDBName)
+}
+
+// OpenLibraryDB 用纯 Go 的 sqlite 驱动，CGO_ENABLED=0 编译出来的也能写库
+func OpenLibraryDB(path string) (*LibraryDB, error) {
+	// 批量抓取时多个漫画会同时写库，用 WAL 并等待锁
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, errors.Wrap(err, "open library db failed")
+	}
+
+	if _, err = db.Exec(librarySchema); err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "create library schema failed")
+	}
+
+	return &LibraryDB{db: db}, nil
+}
+
+// OpenLibraryDBReadOnly 只读打开已有的库，不建表，给 query 这类只查询的命令用
+func OpenLibraryDBReadOnly(path string) (*LibraryDB, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro&_pragma=busy_timeout(10000)")
+	if err != nil {
+		return nil, errors.Wrap(err, "open library db failed")
+	}
+
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "open library db failed")
+	}
+
+	return &LibraryDB{db: db}, nil
+}
+
+func (l *LibraryDB) Close() error {
+	return l.db.Close()
+}
+
+type ScrapeRecord struct {
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Error      error
+}
+
+// RecordComic 把一次抓取的漫画、章节、图片状态写入数据库，状态和大小取自 journal
+func (l *LibraryDB) RecordComic(c *Comics, record *ScrapeRecord) error {
+	tx, err := l.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "begin transaction failed")
+	}
+
+	defer tx.Rollback()
+
+	var (
+		images, failed int
+		size           int64
+		now            = record.FinishedAt.Format(time.RFC3339)
+	)
+
+	for _, chapter := range c.Chapters {
+		status := JobPending
+		if entry, ok := c.journal.Get(chapterJobKey(chapter)); ok {
+			status = entry.State
+		}
+
+		// 没解析出图片的章节不覆盖已有记录
+		conflict := "REPLACE"
+		if len(chapter.Images) == 0 {
+			conflict = "IGNORE"
+		}
+
+		_, err = tx.Exec(`INSERT OR `+conflict+` INTO chapters (comic_number, idx, page_url, images, status) VALUES (?, ?, ?, ?, ?)`,
+			c.Number, chapter.Index, chapter.PageUrl, len(chapter.Images), status)
+		if err != nil {
+			return errors.Wrap(err, "record chapter failed")
+		}
+
+		for _, image := range chapter.Images {
+			entry, ok := c.journal.Get(imageJobKey(image))
+			if !ok {
+				entry = &JobEntry{State: JobPending}
+			}
+
+			imagePath, _ := c.getImageDataPath(image)
+			updatedAt := ""
+			if !entry.UpdatedAt.IsZero() {
+				updatedAt = entry.UpdatedAt.Format(time.RFC3339)
+			}
+
+			_, err = tx.Exec(`INSERT OR REPLACE INTO images
+				(comic_number, chapter, idx, url, path, status, size, sha256, error, width, height, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				c.Number, chapter.Index, image.Index, image.Url, imagePath, entry.State, entry.Size, entry.Checksum,
+				entry.Error, image.Width, image.Height, updatedAt)
+			if err != nil {
+				return errors.Wrap(err, "record image failed")
+			}
+
+			images++
+			if entry.State == JobFailed {
+				failed++
+			}
+		}
+	}
+
+	// 统计整本漫画，包括这次没有选中但以前抓过的章节
+	var totalChapters, totalImages int
+	err = tx.QueryRow(`SELECT (SELECT COUNT(*) FROM chapters WHERE comic_number = ?),
+		(SELECT COUNT(*) FROM images WHERE comic_number = ? AND status = 'done'),
+		(SELECT COALESCE(SUM(size), 0) FROM images WHERE comic_number = ? AND status = 'done')`,
+		c.Number, c.Number, c.Number).Scan(&totalChapters, &totalImages, &size)
+	if err != nil {
+		return errors.Wrap(err, "sum comic failed")
+	}
+
+	_, err = tx.Exec(`INSERT OR REPLACE INTO comics
+		(number, title, pinyin_title, url, category, subtitle, description, cover_url, last_modified, layout, chapters, images, size, scraped_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.Number, c.Title, c.EnTitle, c.MainUrl, c.Category, c.Subtitle, c.Desc, c.CoverUrl, c.LastModifyTime, c.Layout,
+		totalChapters, totalImages, size, now)
+	if err != nil {
+		return errors.Wrap(err, "record comic failed")
+	}
+
+	var scrapeErr sql.NullString
+	if record.Error != nil {
+		scrapeErr = sql.NullString{String: record.Error.Error(), Valid: true}
+	}
+
+	_, err = tx.Exec(`INSERT INTO scrapes (comic_number, started_at, finished_at, chapters, images, failed, error) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		c.Number, record.StartedAt.Format(time.RFC3339), now, len(c.Chapters), images, failed, scrapeErr)
+	if err != nil {
+		return errors.Wrap(err, "record scrape failed")
+	}
+
+	return errors.Wrap(tx.Commit(), "commit library db failed")
+}
+
+// Query 执行任意 sql，所有列都转成字符串返回
+func (l *LibraryDB) Query(query string, args ...interface{}) ([]string, [][]string, error) {
+	rows, err := l.db.Query(query, args...)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "query failed")
+	}
+
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := make([][]string, 0, 16)
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		for i := range values {
+			values[i] = new(interface{})
+		}
+
+		if err = rows.Scan(values...); err != nil {
+			return nil, nil, errors.Wrap(err, "scan row failed")
+		}
+
+		row := make([]string, len(columns))
+		for i, v := range values {
+			switch val := (*v.(*interface{})).(type) {
+			case nil:
+				row[i] = ""
+			case []byte:
+				row[i] = string(val)
+			default:
+				row[i] = fmt.Sprint(val)
+			}
+		}
+
+		result = append(result, row)
+	}
+
+	return columns, result, rows.Err()
+}
+
+func (c *Comics) recordLibrary(record *ScrapeRecord) {
+	if c.Number == 0 || c.Title == "" || c.dryRun {
+		return
+	}
+
+	l, err := OpenLibraryDB(GetLibraryDBPath(c.RootPath))
+	if err != nil {
+		log.Errorf("open library db failed, err:%v", err)
+		return
+	}
+
+	defer l.Close()
+
+	if err = l.RecordComic(c, record); err != nil {
+		log.Errorf("comic:%v, record library db failed, err:%v", c.EnTitle, err)
+		return
+	}
+
+	log.Debugf("comic:%v, record library db success", c.EnTitle)
+}
@@ -0,0 +1,137 @@
+package scrape
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultQueueName = "queue.txt"
+)
+
+type ListingEntry struct {
+	Url      string `json:"url"`
+	Number   int    `json:"number"`
+	Title    string `json:"title"`
+	CoverUrl string `json:"cover_url"`
+}
+
+// Discover 从分类或标签列表页开始，沿着分页一直抓到最后一页，maxPages 为 0 表示不限制
+func (c *Comics) Discover(listUrl string, maxPages int) ([]*ListingEntry, error) {
+	if c.configErr != nil {
+		return nil, errors.Wrap(c.configErr, "invalid config")
+	}
+
+	if err := c.initAdapter(listUrl); err != nil {
+		return nil, err
+	}
+
+	parser, ok := c.adapter.(ListingParser)
+	if !ok {
+		return nil, errors.Errorf("adapter:%v, listing pages not supported", c.adapter.Name())
+	}
+
+	var (
+		entries   = make([]*ListingEntry, 0, 64)
+		seenPages = make(map[string]bool, 8)
+		seenComic = make(map[string]bool, 64)
+		pageUrl   = c.mirrors.canonical(listUrl)
+	)
+
+	for pages := 0; pageUrl != "" && !seenPages[pageUrl]; pages++ {
+		if maxPages > 0 && pages >= maxPages {
+			log.Infof("listUrl:%v, reach max pages:%v", listUrl, maxPages)
+			break
+		}
+
+		seenPages[pageUrl] = true
+
+		body, err := c.downloadPage(pageUrl, DefaultMainPageTTL)
+		if err != nil {
+			return entries, errors.Wrapf(err, "download listing page:%v failed", pageUrl)
+		}
+
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+		if err != nil {
+			return entries, errors.Wrapf(err, "parse listing page:%v failed", pageUrl)
+		}
+
+		items, next, err := parser.ParseListing(doc, pageUrl)
+		if err != nil {
+			return entries, err
+		}
+
+		for _, item := range items {
+			item.Url = c.mirrors.canonical(item.Url)
+			if seenComic[item.Url] {
+				continue
+			}
+
+			seenComic[item.Url] = true
+			entries = append(entries, item)
+		}
+
+		log.Debugf("listPage:%v, entries:%v, next:%v", pageUrl, len(items), next)
+		pageUrl = c.mirrors.canonical(next)
+	}
+
+	return entries, nil
+}
+
+func GetQueuePath(rootPath string) string {
+	return filepath.Join(rootPath, DefaultQueueName)
+}
+
+// AppendQueue 把还没排队的漫画地址追加到队列文件，每行一个，返回新增数量
+func AppendQueue(path string, entries []*ListingEntry) (int, error) {
+	queued := make(map[string]bool, len(entries))
+
+	f, err := os.Open(path)
+	if err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			queued[strings.TrimSpace(scanner.Text())] = true
+		}
+
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return 0, errors.Wrap(err, "read queue failed")
+		}
+	} else if !os.IsNotExist(err) {
+		return 0, errors.Wrap(err, "open queue failed")
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return 0, errors.Wrap(err, "create queue dir failed")
+	}
+
+	f, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, errors.Wrap(err, "open queue failed")
+	}
+	defer f.Close()
+
+	added := 0
+	for _, entry := range entries {
+		if queued[entry.Url] {
+			continue
+		}
+
+		if _, err = f.WriteString(entry.Url + "\n"); err != nil {
+			return added, errors.Wrap(err, "write queue failed")
+		}
+
+		queued[entry.Url] = true
+		added++
+	}
+
+	return added, nil
+}
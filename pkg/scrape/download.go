@@ -1,32 +1,26 @@
 package scrape
 
 import (
-	"crypto/tls"
+	"context"
 	"io"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
 
 	log "github.com/sirupsen/logrus"
 )
 
-func DownloadPage(url string, debug bool) ([]byte, error) {
+func DownloadPage(client *http.Client, url string, debug bool) ([]byte, error) {
+	return DownloadPageContext(context.Background(), client, url, debug)
+}
+
+func DownloadPageContext(ctx context.Context, client *http.Client, url string, debug bool) ([]byte, error) {
 	if debug {
 		return badMan, nil
 	}
 
 	logField := log.Fields{"content": "html-content", "url": url}
 
-	client := http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
-		},
-	}
-
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		log.WithFields(logField).WithField("position", "new http request failed").Error(err)
 		return nil, err
@@ -52,44 +46,120 @@ func DownloadPage(url string, debug bool) ([]byte, error) {
 
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		err = newStatusError(url, resp)
+		log.WithFields(logField).WithField("position", "BadStatusCode").Error(err)
+		return nil, err
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		log.WithFields(logField).WithField("position", "ReadBodyFailed").Error(err)
 		return nil, err
 	}
 
+	if resp.ContentLength >= 0 && int64(len(body)) != resp.ContentLength {
+		err = &DownloadError{Kind: ErrTruncated, Url: url, Expected: resp.ContentLength, Received: int64(len(body))}
+		log.WithFields(logField).WithField("position", "TruncatedBody").Error(err)
+		return nil, err
+	}
+
 	log.WithFields(logField).Debug("success")
 	return body, nil
 }
 
-func DownloadImage(imagePath, imageUrl string) error {
-	client := http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
-		},
-	}
+func DownloadImage(client *http.Client, imagePath, imageUrl string) error {
+	return DownloadImageContext(context.Background(), client, imagePath, imageUrl)
+}
 
+func DownloadImageContext(ctx context.Context, client *http.Client, imagePath, imageUrl string) error {
 	logField := log.Fields{"content": "download-image", "image-url": imageUrl}
 
-	req, err := http.NewRequest("GET", imageUrl, nil)
+	req, err := newImageRequest(ctx, "GET", imageUrl)
 	if err != nil {
 		log.WithFields(logField).WithField("position", "NewHttpRequestFailed").Error(err)
 		return err
 	}
 
-	u, err := url.ParseRequestURI(imageUrl)
+	resp, err := client.Do(req)
+	if err != nil {
+		log.WithFields(logField).WithField("position", "DoHttpRequestFailed").Error(err)
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = newStatusError(imageUrl, resp)
+		log.WithFields(logField).WithField("position", "BadStatusCode").Error(err)
+		return err
+	}
+
+	fd, err := createTempFile(imagePath)
 	if err != nil {
-		log.WithFields(logField).WithField("position", "ParseImageURIFailed").Error(err)
+		log.WithFields(logField).WithField("position", "CreateTempFileFailed").Error(err)
+		return err
+	}
+
+	err = commitTempFile(fd, imagePath, func(w io.Writer) error {
+		return copyImageBody(w, resp, imageUrl)
+	})
+	if err != nil {
+		log.WithFields(logField).WithField("position", "WriteImageToFileFailed").Error(err)
 		return err
 	}
 
+	log.WithFields(logField).Debug("write image to file success")
+	return nil
+}
+
+// HeadImage 只请求响应头，返回图片大小，服务端没有给出长度时返回 -1
+func HeadImage(client *http.Client, imageUrl string) (int64, error) {
+	return HeadImageContext(context.Background(), client, imageUrl)
+}
+
+func HeadImageContext(ctx context.Context, client *http.Client, imageUrl string) (int64, error) {
+	logField := log.Fields{"content": "head-image", "image-url": imageUrl}
+
+	req, err := newImageRequest(ctx, "HEAD", imageUrl)
+	if err != nil {
+		log.WithFields(logField).WithField("position", "NewHttpRequestFailed").Error(err)
+		return 0, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		log.WithFields(logField).WithField("position", "DoHttpRequestFailed").Error(err)
+		return 0, err
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = newStatusError(imageUrl, resp)
+		log.WithFields(logField).WithField("position", "BadStatusCode").Error(err)
+		return 0, err
+	}
+
+	log.WithFields(logField).Debugf("content length:%v", resp.ContentLength)
+	return resp.ContentLength, nil
+}
+
+func newImageRequest(ctx context.Context, method, imageUrl string) (*http.Request, error) {
+	u, err := url.ParseRequestURI(imageUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, imageUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
 	req.Header.Add("Host", u.Host)
 	req.Header.Add("User-Agent", "Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/112.0")
 	req.Header.Add("Accept", "image/avif,image/webp,*/*")
 	req.Header.Add("Accept-Language", "zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2")
-	req.Header.Add("Accept-Encoding", "gzip, deflate, br")
 	req.Header.Add("Connection", "keep-alive")
 	req.Header.Add("Referer", "https://www.san499.com/")
 	req.Header.Add("Sec-Fetch-Dest", "image")
@@ -97,32 +167,35 @@ func DownloadImage(imagePath, imageUrl string) error {
 	req.Header.Add("Sec-Fetch-Site", "cross-site")
 	req.Header.Add("TE", "trailers")
 
-	resp, err := client.Do(req)
-	if err != nil {
-		log.WithFields(logField).WithField("position", "DoHttpRequestFailed").Error(err)
+	return req, nil
+}
+
+func copyImageBody(w io.Writer, resp *http.Response, imageUrl string) error {
+	head := make([]byte, 12)
+	n, err := io.ReadFull(resp.Body, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
 		return err
 	}
 
-	defer resp.Body.Close()
+	head = head[:n]
+	if !isImageSignature(head) {
+		return &DownloadError{Kind: ErrNotImage, Url: imageUrl, StatusCode: resp.StatusCode, Received: int64(n)}
+	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.WithFields(logField).WithField("position", "ReadBodyFailed").Error(err)
+	if _, err = w.Write(head); err != nil {
 		return err
 	}
 
-	log.WithFields(logField).Debugf("response size:%v", len(body))
-
-	dir, _ := filepath.Split(imagePath)
-	if err = os.MkdirAll(dir, 0755); err != nil && !os.IsExist(err) {
-		return err
+	written, err := io.Copy(w, resp.Body)
+	written += int64(n)
+	if err != nil {
+		return &DownloadError{Kind: ErrTruncated, Url: imageUrl, StatusCode: resp.StatusCode, Expected: resp.ContentLength, Received: written}
 	}
 
-	if err = os.WriteFile(imagePath, body, 0666); err != nil {
-		log.WithFields(logField).WithField("position", "WriteImageToFileFailed").Error(err)
-		return err
+	if resp.ContentLength >= 0 && written != resp.ContentLength {
+		return &DownloadError{Kind: ErrTruncated, Url: imageUrl, StatusCode: resp.StatusCode, Expected: resp.ContentLength, Received: written}
 	}
 
-	log.WithFields(logField).Debug("write image to file success")
+	log.WithField("image-url", imageUrl).Debugf("response size:%v", written)
 	return nil
 }
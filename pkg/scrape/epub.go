@@ -0,0 +1,294 @@
+package scrape
+
+import (
+	"archive/zip"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+var epubPackageTmpl = template.Must(template.New("opf").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="uid" xml:lang="zh">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="uid">{{.Identifier | html}}</dc:identifier>
+    <dc:title>{{.Title | html}}</dc:title>
+    <dc:language>zh</dc:language>
+    {{- if .Desc}}
+    <dc:description>{{.Desc | html}}</dc:description>
+    {{- end}}
+    {{- if .Category}}
+    <dc:subject>{{.Category | html}}</dc:subject>
+    {{- end}}
+    {{- if .Date}}
+    <dc:date>{{.Date}}</dc:date>
+    {{- end}}
+    <dc:source>{{.Source | html}}</dc:source>
+    <meta property="dcterms:modified">{{.Modified}}</meta>
+    <meta property="rendition:layout">pre-paginated</meta>
+    <meta property="rendition:orientation">portrait</meta>
+    <meta property="rendition:spread">none</meta>
+    {{- if .Cover}}
+    <meta name="cover" content="cover-image"/>
+    {{- end}}
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    {{- if .Cover}}
+    <item id="cover-image" href="{{.Cover.Image}}" media-type="{{.Cover.MediaType}}" properties="cover-image"/>
+    <item id="cover" href="{{.Cover.Page}}" media-type="application/xhtml+xml"/>
+    {{- end}}
+    {{- range .Pages}}
+    <item id="{{.Id}}-image" href="{{.Image}}" media-type="{{.MediaType}}"/>
+    <item id="{{.Id}}" href="{{.Page}}" media-type="application/xhtml+xml"/>
+    {{- end}}
+  </manifest>
+  <spine>
+    {{- if .Cover}}
+    <itemref idref="cover"/>
+    {{- end}}
+    {{- range .Pages}}
+    <itemref idref="{{.Id}}"/>
+    {{- end}}
+  </spine>
+</package>
+`))
+
+var epubNavTmpl = template.Must(template.New("nav").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="zh">
+<head>
+  <title>{{.Title | html}}</title>
+</head>
+<body>
+  <nav epub:type="toc" id="toc">
+    <h1>{{.Title | html}}</h1>
+    <ol>
+      {{- range .Chapters}}
+      <li><a href="{{.Page}}">{{.Title | html}}</a></li>
+      {{- end}}
+    </ol>
+  </nav>
+</body>
+</html>
+`))
+
+var epubPageTmpl = template.Must(template.New("page").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xml:lang="zh">
+<head>
+  <title>{{.Title | html}}</title>
+  <meta name="viewport" content="width={{.Width}}, height={{.Height}}"/>
+  <style>html, body { margin: 0; padding: 0; } img { display: block; width: {{.Width}}px; height: {{.Height}}px; }</style>
+</head>
+<body>
+  <img src="{{.Image}}" alt="{{.Title | html}}"/>
+</body>
+</html>
+`))
+
+type epubPage struct {
+	Id        string
+	Title     string
+	Page      string
+	Image     string
+	MediaType string
+	Width     int
+	Height    int
+	path      string
+}
+
+type epubChapter struct {
+	Title string
+	Page  string
+}
+
+type epubPackage struct {
+	Identifier string
+	Title      string
+	Desc       string
+	Category   string
+	Date       string
+	Source     string
+	Modified   string
+	Cover      *epubPage
+	Pages      []*epubPage
+	Chapters   []*epubChapter
+}
+
+func (c *Comics) exportEpub() error {
+	pkg := &epubPackage{
+		Identifier: fmt.Sprintf("urn:sansi:%d", c.Number),
+		Title:      c.Title,
+		Desc:       c.Desc,
+		Category:   c.Category,
+		Date:       c.LastModifyTime,
+		Source:     c.MainUrl,
+		Modified:   time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+	}
+
+	if coverPath, ok := c.getExportCover(); ok {
+		cover := &exportImage{Image: &Image{}, Path: coverPath}
+		w, h := cover.Size()
+
+		pkg.Cover = &epubPage{
+			Id:        "cover",
+			Title:     c.Title,
+			Page:      "cover.xhtml",
+			Image:     "images/cover" + cover.Ext(),
+			MediaType: imageMediaType(cover.Ext()),
+			Width:     w,
+			Height:    h,
+			path:      coverPath,
+		}
+	}
+
+	failed := make([]string, 0, 4)
+	for _, chapter := range c.Chapters {
+		images, err := c.getExportImages(chapter)
+		if err != nil {
+			log.Errorf("chapter:%v, skip in epub, err:%v", chapter.Index, err)
+			failed = append(failed, fmt.Sprintf("chapter %v: %v", chapter.Index, err))
+			continue
+		}
+
+		title := fmt.Sprintf("第%d话", chapter.Index)
+		for i, image := range images {
+			w, h := image.Size()
+			name := fmt.Sprintf("%s-%03d", chapter.Name(), image.Index)
+
+			page := &epubPage{
+				Id:        "p" + name,
+				Title:     title,
+				Page:      "pages/" + name + ".xhtml",
+				Image:     "images/" + name + image.Ext(),
+				MediaType: imageMediaType(image.Ext()),
+				Width:     w,
+				Height:    h,
+				path:      image.Path,
+			}
+
+			if i == 0 {
+				pkg.Chapters = append(pkg.Chapters, &epubChapter{Title: title, Page: page.Page})
+			}
+
+			pkg.Pages = append(pkg.Pages, page)
+		}
+	}
+
+	if len(pkg.Pages) == 0 {
+		return exportFailed(ExportEpub, len(c.Chapters), failed)
+	}
+
+	// 完整的章节照常写入 epub，跳过的章节通过错误报告出来
+	epubPath := c.getExportDataPath(c.EnTitle + ".epub")
+	if err := writeEpub(epubPath, pkg); err != nil {
+		return errors.Wrap(err, "write epub failed")
+	}
+
+	log.Debugf("write epub:%v success", epubPath)
+	return exportFailed(ExportEpub, len(c.Chapters), failed)
+}
+
+// writeEpub 先写临时文件，全部成功后才改名，失败时不会留下半截的 epub
+func writeEpub(epubPath string, pkg *epubPackage) error {
+	fd, err := createTempFile(epubPath)
+	if err != nil {
+		return err
+	}
+
+	return commitTempFile(fd, epubPath, func(w io.Writer) error {
+		return writeEpubArchive(w, pkg)
+	})
+}
+
+func writeEpubArchive(fd io.Writer, pkg *epubPackage) error {
+	zw := zip.NewWriter(fd)
+
+	mimetype := []byte("application/epub+zip")
+	w, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(mimetype),
+		CompressedSize64:   uint64(len(mimetype)),
+		UncompressedSize64: uint64(len(mimetype)),
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err = w.Write(mimetype); err != nil {
+		return err
+	}
+
+	if err = addZipContent(zw, "META-INF/container.xml", []byte(epubContainer)); err != nil {
+		return err
+	}
+
+	if err = addZipTemplate(zw, "OEBPS/content.opf", epubPackageTmpl, pkg); err != nil {
+		return err
+	}
+
+	if err = addZipTemplate(zw, "OEBPS/nav.xhtml", epubNavTmpl, pkg); err != nil {
+		return err
+	}
+
+	pages := pkg.Pages
+	if pkg.Cover != nil {
+		pages = append([]*epubPage{pkg.Cover}, pages...)
+	}
+
+	for _, page := range pages {
+		if err = addZipTemplate(zw, filepath.ToSlash(filepath.Join("OEBPS", page.Page)), epubPageTmpl, pageView(page)); err != nil {
+			return err
+		}
+
+		if err = addZipFile(zw, "OEBPS/"+page.Image, page.path); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func pageView(page *epubPage) *epubPage {
+	view := *page
+	view.Image = "../" + page.Image
+	if filepath.Dir(page.Page) == "." {
+		view.Image = page.Image
+	}
+
+	return &view
+}
+
+func addZipContent(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+func addZipTemplate(zw *zip.Writer, name string, tmpl *template.Template, data interface{}) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+
+	return tmpl.Execute(w, data)
+}
@@ -0,0 +1,112 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrBadStatus   = errors.New("unexpected http status")
+	ErrNotFound    = errors.New("not found")
+	ErrRateLimited = errors.New("rate limited")
+	ErrServerError = errors.New("server error")
+	ErrTruncated   = errors.New("truncated body")
+	ErrNotImage    = errors.New("body is not an image")
+)
+
+type DownloadError struct {
+	Kind       error
+	Url        string
+	StatusCode int
+	Expected   int64
+	Received   int64
+	RetryAfter time.Duration
+}
+
+func (e *DownloadError) Error() string {
+	switch e.Kind {
+	case ErrTruncated:
+		return fmt.Sprintf("url:%v, %v, expected:%v, received:%v", e.Url, e.Kind, e.Expected, e.Received)
+	case ErrNotImage:
+		return fmt.Sprintf("url:%v, %v, received:%v", e.Url, e.Kind, e.Received)
+	}
+
+	return fmt.Sprintf("url:%v, %v, status code:%v", e.Url, e.Kind, e.StatusCode)
+}
+
+func (e *DownloadError) Unwrap() error {
+	return e.Kind
+}
+
+func newStatusError(url string, resp *http.Response) *DownloadError {
+	e := &DownloadError{Kind: ErrBadStatus, Url: url, StatusCode: resp.StatusCode}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		e.Kind = ErrNotFound
+	case resp.StatusCode == http.StatusTooManyRequests:
+		e.Kind = ErrRateLimited
+		e.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+	case resp.StatusCode >= 500:
+		e.Kind = ErrServerError
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			e.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		}
+	}
+
+	return e
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
+
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var de *DownloadError
+	if errors.As(err, &de) {
+		return de.Kind == ErrRateLimited || de.Kind == ErrServerError || de.Kind == ErrTruncated
+	}
+
+	// client.Do 的错误都是 *url.Error，证书、代理认证、域名解析失败等重试也不会成功，只重试超时和连接被重置或拒绝
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func retryAfter(err error) time.Duration {
+	var de *DownloadError
+	if errors.As(err, &de) {
+		return de.RetryAfter
+	}
+
+	return 0
+}
@@ -0,0 +1,85 @@
+package scrape
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestIsRetryable(t *testing.T) {
+	urlErr := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://www.san499.com/1.html", Err: err}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"rate limited", &DownloadError{Kind: ErrRateLimited, StatusCode: http.StatusTooManyRequests}, true},
+		{"server error", &DownloadError{Kind: ErrServerError, StatusCode: http.StatusBadGateway}, true},
+		{"truncated", &DownloadError{Kind: ErrTruncated}, true},
+		{"not found", &DownloadError{Kind: ErrNotFound, StatusCode: http.StatusNotFound}, false},
+		{"forbidden", &DownloadError{Kind: ErrBadStatus, StatusCode: http.StatusForbidden}, false},
+		{"not image", &DownloadError{Kind: ErrNotImage}, false},
+		{"timeout", urlErr(os.ErrDeadlineExceeded), true},
+		{"connection reset", urlErr(&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), true},
+		{"connection refused", urlErr(&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), true},
+		{"unexpected eof", urlErr(io.ErrUnexpectedEOF), true},
+		{"wrapped unexpected eof", errors.Wrap(io.ErrUnexpectedEOF, "read body failed"), true},
+		{"no such host", urlErr(&net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "www.san499.com", IsNotFound: true}}), false},
+		{"certificate", urlErr(x509.UnknownAuthorityError{}), false},
+		{"unsupported scheme", urlErr(errors.New("unsupported protocol scheme \"ftp\"")), false},
+		{"canceled", urlErr(context.Canceled), false},
+		{"plain error", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsFailoverError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"server error", &DownloadError{Kind: ErrServerError, StatusCode: http.StatusServiceUnavailable}, true},
+		{"rate limited", &DownloadError{Kind: ErrRateLimited, StatusCode: http.StatusTooManyRequests}, false},
+		{"not found", &DownloadError{Kind: ErrNotFound, StatusCode: http.StatusNotFound}, false},
+		{"connection refused", &url.Error{Op: "Get", Err: syscall.ECONNREFUSED}, true},
+		{"dial refused", &url.Error{Op: "Get", Err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}}, true},
+		{"no such host", &url.Error{Op: "Get", Err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "www.san499.com", IsNotFound: true}}}, true},
+		{"dns error", &url.Error{Op: "Get", Err: &net.DNSError{Err: "server misbehaving", Name: "www.san499.com"}}, true},
+		{"unknown authority", &url.Error{Op: "Get", Err: x509.UnknownAuthorityError{}}, true},
+		{"hostname mismatch", &url.Error{Op: "Get", Err: x509.HostnameError{Host: "www.san499.com"}}, true},
+		{"certificate expired", &url.Error{Op: "Get", Err: x509.CertificateInvalidError{Reason: x509.Expired}}, true},
+		{"certificate verification", &url.Error{Op: "Get", Err: &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}}, true},
+		{"timeout", &url.Error{Op: "Get", Err: os.ErrDeadlineExceeded}, true},
+		{"canceled", &url.Error{Op: "Get", Err: context.Canceled}, false},
+		{"plain error", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isFailoverError(tt.err); got != tt.want {
+				t.Errorf("isFailoverError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
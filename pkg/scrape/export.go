@@ -0,0 +1,157 @@
+package scrape
+
+import (
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	ExportCbz  = "cbz"
+	ExportEpub = "epub"
+	ExportPdf  = "pdf"
+)
+
+func IsValidExportFormat(format string) bool {
+	return format == ExportCbz || format == ExportEpub || format == ExportPdf
+}
+
+func (c *Comics) Export(format string) error {
+	if !IsValidExportFormat(format) {
+		return errors.Errorf("unsupported export format:%v", format)
+	}
+
+	dir := c.getExportDataPath("")
+	if err := os.MkdirAll(dir, 0755); err != nil && !os.IsExist(err) {
+		return err
+	}
+
+	// 从 manifest 载入时没有打开 journal，只读加载用来校验图片
+	if c.journal == nil {
+		journal, err := ReadJournal(c.getJournalPath())
+		if err != nil {
+			return errors.Wrap(err, "read journal failed")
+		}
+
+		c.journal = journal
+	}
+
+	switch format {
+	case ExportCbz:
+		return c.exportCbz()
+	case ExportEpub:
+		return c.exportEpub()
+	case ExportPdf:
+		return c.exportPdf()
+	}
+
+	return nil
+}
+
+// exportFailed 汇总失败的章节，有失败时命令以非 0 退出
+func exportFailed(format string, total int, failed []string) error {
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return errors.Errorf("export %v failed, %v of %v: %v", format, len(failed), total, strings.Join(failed, "; "))
+}
+
+// getExportImages 返回章节里已下载完成的图片，缺任何一张都返回错误，不导出看起来完整实际缺页的章节
+func (c *Comics) getExportImages(chapter *Chapter) ([]*exportImage, error) {
+	images := make([]*exportImage, 0, len(chapter.Images))
+
+	for _, image := range chapter.Images {
+		imagePath, err := c.getImageDataPath(image)
+		if err != nil {
+			log.Errorf("imageUrl:%v, get image path failed, err:%v", image.Url, err)
+			continue
+		}
+
+		if !c.journal.IsDone(imageJobKey(image), image.Url, imagePath) {
+			log.Infof("imageUrl:%v, not downloaded or incomplete", image.Url)
+			continue
+		}
+
+		images = append(images, &exportImage{Image: image, Path: imagePath})
+	}
+
+	if len(images) == 0 {
+		return nil, errors.New("no image in disk")
+	}
+
+	if missing := len(chapter.Images) - len(images); missing > 0 {
+		return nil, errors.Errorf("%v of %v images not downloaded", missing, len(chapter.Images))
+	}
+
+	return images, nil
+}
+
+func (c *Comics) getExportCover() (string, bool) {
+	coverPath := c.getCoverPath()
+	if !c.journal.IsDone(coverJobKey(), c.CoverUrl, coverPath) {
+		return "", false
+	}
+
+	return coverPath, true
+}
+
+type exportImage struct {
+	*Image
+	Path string
+}
+
+func (i *exportImage) Ext() string {
+	return filepath.Ext(i.Path)
+}
+
+func (i *exportImage) Size() (int, int) {
+	if i.Width > 0 && i.Height > 0 {
+		return i.Width, i.Height
+	}
+
+	w, h, err := decodeImageSize(i.Path)
+	if err != nil {
+		log.Errorf("image:%v, decode size failed, err:%v", i.Path, err)
+		return i.Width, i.Height
+	}
+
+	return w, h
+}
+
+func decodeImageSize(path string) (int, int, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	defer fd.Close()
+
+	cfg, _, err := image.DecodeConfig(fd)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return cfg.Width, cfg.Height, nil
+}
+
+func imageMediaType(ext string) string {
+	switch strings.ToLower(ext) {
+	case ".png":
+		return "image/png"
+	case ".gif":
+		return "image/gif"
+	case ".webp":
+		return "image/webp"
+	}
+
+	return "image/jpeg"
+}
@@ -0,0 +1,74 @@
+package scrape
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+var imageSignatures = [][]byte{
+	{0xFF, 0xD8, 0xFF},
+	{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'},
+	[]byte("GIF87a"),
+	[]byte("GIF89a"),
+}
+
+func isImageSignature(head []byte) bool {
+	for _, sig := range imageSignatures {
+		if bytes.HasPrefix(head, sig) {
+			return true
+		}
+	}
+
+	return len(head) >= 12 && bytes.Equal(head[:4], []byte("RIFF")) && bytes.Equal(head[8:12], []byte("WEBP"))
+}
+
+func createTempFile(path string) (*os.File, error) {
+	dir, file := filepath.Split(path)
+	if err := os.MkdirAll(dir, 0755); err != nil && !os.IsExist(err) {
+		return nil, err
+	}
+
+	return os.CreateTemp(dir, "."+file+".*.part")
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	fd, err := createTempFile(path)
+	if err != nil {
+		return err
+	}
+
+	return commitTempFile(fd, path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+func commitTempFile(fd *os.File, path string, write func(w io.Writer) error) error {
+	tmpPath := fd.Name()
+
+	err := write(fd)
+	if err == nil {
+		err = fd.Sync()
+	}
+
+	if cerr := fd.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil {
+		err = os.Chmod(tmpPath, 0644)
+	}
+
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,302 @@
+package scrape
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultJournalName = "journal.jsonl"
+)
+
+type JobState string
+
+const (
+	JobPending JobState = "pending"
+	JobDone    JobState = "done"
+	JobFailed  JobState = "failed"
+)
+
+type JobEntry struct {
+	Key       string    `json:"key"`
+	State     JobState  `json:"state"`
+	Url       string    `json:"url,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Size      int64     `json:"size,omitempty"`
+	Checksum  string    `json:"sha256,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Journal struct {
+	mu      sync.Mutex
+	path    string
+	fd      *os.File
+	entries map[string]*JobEntry
+}
+
+func chapterJobKey(chapter *Chapter) string {
+	return "chapter:" + chapter.Name()
+}
+
+func imageJobKey(image *Image) string {
+	return fmt.Sprintf("image:%s/%03d", chapterName(image.Chapter), image.Index)
+}
+
+func coverJobKey() string {
+	return "cover"
+}
+
+func OpenJournal(path string) (*Journal, error) {
+	j := &Journal{
+		path:    path,
+		entries: make(map[string]*JobEntry),
+	}
+
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+
+	dir, _ := filepath.Split(path)
+	if err := os.MkdirAll(dir, 0755); err != nil && !os.IsExist(err) {
+		return nil, err
+	}
+
+	fd, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, errors.Wrap(err, "open journal failed")
+	}
+
+	j.fd = fd
+	return j, nil
+}
+
+// ReadJournal 只读加载 journal，不能再 Update 或 Close
+func ReadJournal(path string) (*Journal, error) {
+	j := &Journal{
+		path:    path,
+		entries: make(map[string]*JobEntry),
+	}
+
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+func (j *Journal) load() error {
+	fd, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "open journal failed")
+	}
+
+	defer fd.Close()
+
+	scanner := bufio.NewScanner(fd)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		entry := new(JobEntry)
+		if err = json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			log.Infof("journal:%v, skip broken line, err:%v", j.path, err)
+			continue
+		}
+
+		j.entries[entry.Key] = entry
+	}
+
+	return scanner.Err()
+}
+
+func (j *Journal) Get(key string) (*JobEntry, bool) {
+	if j == nil {
+		return nil, false
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry, ok := j.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	cp := *entry
+	return &cp, true
+}
+
+func (j *Journal) Entries() []*JobEntry {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.sortedEntries()
+}
+
+func (j *Journal) sortedEntries() []*JobEntry {
+	entries := make([]*JobEntry, 0, len(j.entries))
+	for _, entry := range j.entries {
+		cp := *entry
+		entries = append(entries, &cp)
+	}
+
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].Key < entries[b].Key
+	})
+
+	return entries
+}
+
+func (j *Journal) Update(entry *JobEntry) error {
+	if j == nil {
+		return nil
+	}
+
+	entry.UpdatedAt = time.Now()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.fd == nil {
+		return errors.New("journal is read only")
+	}
+
+	j.entries[entry.Key] = entry
+	if _, err = j.fd.Write(append(data, '\n')); err != nil {
+		return errors.Wrap(err, "append journal failed")
+	}
+
+	return nil
+}
+
+func (j *Journal) Pending(key, url, path string) error {
+	if _, ok := j.Get(key); ok {
+		return nil
+	}
+
+	return j.Update(&JobEntry{Key: key, State: JobPending, Url: url, Path: path})
+}
+
+func (j *Journal) Done(key, url, path string, size int64, checksum string) error {
+	return j.Update(&JobEntry{Key: key, State: JobDone, Url: url, Path: path, Size: size, Checksum: checksum})
+}
+
+func (j *Journal) Failed(key, url, path string, cause error) error {
+	return j.Update(&JobEntry{Key: key, State: JobFailed, Url: url, Path: path, Error: cause.Error()})
+}
+
+// IsDone 只有地址、文件大小和校验和都与日志一致时才认为已完成，半截文件或站点换过的图片会被重新下载
+func (j *Journal) IsDone(key, url, path string) bool {
+	entry, ok := j.doneEntry(key, url, path)
+	if !ok {
+		return false
+	}
+
+	checksum, _, err := fileChecksum(path)
+	if err != nil {
+		return false
+	}
+
+	return checksum == entry.Checksum
+}
+
+// IsDoneQuick 同 IsDone 但不计算校验和，给 watch、serve 这类频繁检查整本漫画的地方用
+func (j *Journal) IsDoneQuick(key, url, path string) bool {
+	_, ok := j.doneEntry(key, url, path)
+	return ok
+}
+
+func (j *Journal) doneEntry(key, url, path string) (*JobEntry, bool) {
+	entry, ok := j.Get(key)
+	if !ok || entry.State != JobDone || entry.Url != url {
+		return nil, false
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil || stat.Size() != entry.Size {
+		return nil, false
+	}
+
+	return entry, true
+}
+
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.fd == nil {
+		return nil
+	}
+
+	if err := j.fd.Close(); err != nil {
+		return err
+	}
+
+	buf := bytes.Buffer{}
+	for _, entry := range j.sortedEntries() {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+
+		buf.Write(append(data, '\n'))
+	}
+
+	if err := writeFileAtomic(j.path, buf.Bytes()); err != nil {
+		return errors.Wrap(err, "compact journal failed")
+	}
+
+	return nil
+}
+
+func fileChecksum(path string) (string, int64, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+
+	defer fd.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, fd)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+func bytesChecksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,164 @@
+package scrape
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestJournalIsDone(t *testing.T) {
+	dir := t.TempDir()
+	imagePath := filepath.Join(dir, "001.jpg")
+	data := []byte("\xff\xd8\xff\xe0 image data")
+	if err := os.WriteFile(imagePath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	const (
+		key      = "image:0001/001"
+		imageUrl = "https://img.34img.com/a/001.jpg"
+	)
+
+	tests := []struct {
+		name    string
+		record  func(j *Journal) error
+		url     string
+		rewrite []byte
+		want    bool
+	}{
+		{
+			name:   "no entry",
+			record: func(j *Journal) error { return nil },
+			url:    imageUrl,
+		},
+		{
+			name:   "pending",
+			record: func(j *Journal) error { return j.Pending(key, imageUrl, imagePath) },
+			url:    imageUrl,
+		},
+		{
+			name:   "failed",
+			record: func(j *Journal) error { return j.Failed(key, imageUrl, imagePath, errors.New("timeout")) },
+			url:    imageUrl,
+		},
+		{
+			name:   "done",
+			record: func(j *Journal) error { return j.Done(key, imageUrl, imagePath, int64(len(data)), bytesChecksum(data)) },
+			url:    imageUrl,
+			want:   true,
+		},
+		{
+			name:   "url changed",
+			record: func(j *Journal) error { return j.Done(key, imageUrl, imagePath, int64(len(data)), bytesChecksum(data)) },
+			url:    "https://img.34img.com/b/001.jpg",
+		},
+		{
+			name:    "truncated file",
+			record:  func(j *Journal) error { return j.Done(key, imageUrl, imagePath, int64(len(data)), bytesChecksum(data)) },
+			url:     imageUrl,
+			rewrite: data[:4],
+		},
+		{
+			name:    "same size different content",
+			record:  func(j *Journal) error { return j.Done(key, imageUrl, imagePath, int64(len(data)), bytesChecksum(data)) },
+			url:     imageUrl,
+			rewrite: []byte("\xff\xd8\xff\xe0 image DATA"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(imagePath, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			j, err := OpenJournal(filepath.Join(t.TempDir(), DefaultJournalName))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer j.Close()
+
+			if err = tt.record(j); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.rewrite != nil {
+				if err = os.WriteFile(imagePath, tt.rewrite, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := j.IsDone(key, tt.url, imagePath); got != tt.want {
+				t.Errorf("IsDone() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJournalReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultJournalName)
+
+	j, err := OpenJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = j.Pending("image:0001/001", "u1", "p1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = j.Done("image:0001/001", "u1", "p1", 10, "sum"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 已有记录时 Pending 不会覆盖
+	if err = j.Pending("image:0001/001", "u1", "p1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = j.Failed("image:0001/002", "u2", "p2", errors.New("not found")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	j, err = ReadJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key   string
+		state JobState
+		size  int64
+		error string
+	}{
+		{"image:0001/001", JobDone, 10, ""},
+		{"image:0001/002", JobFailed, 0, "not found"},
+	}
+
+	for _, tt := range tests {
+		entry, ok := j.Get(tt.key)
+		if !ok {
+			t.Fatalf("key:%v, not found after reopen", tt.key)
+		}
+
+		if entry.State != tt.state || entry.Size != tt.size || entry.Error != tt.error {
+			t.Errorf("key:%v, got state:%v size:%v error:%q, want state:%v size:%v error:%q",
+				tt.key, entry.State, entry.Size, entry.Error, tt.state, tt.size, tt.error)
+		}
+	}
+
+	if got := len(j.Entries()); got != len(tests) {
+		t.Errorf("entries = %v, want %v", got, len(tests))
+	}
+
+	if err = j.Update(&JobEntry{Key: "cover"}); err == nil {
+		t.Error("update read only journal should fail")
+	}
+}
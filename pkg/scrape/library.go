@@ -0,0 +1,139 @@
+package scrape
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+func ListManifests(rootPath string) ([]string, error) {
+	entries, err := os.ReadDir(rootPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "read root path failed")
+	}
+
+	paths := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		path := GetManifestPath(rootPath, entry.Name())
+		if _, err = os.Stat(path); err != nil {
+			continue
+		}
+
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func FindManifest(rootPath, key string) (string, error) {
+	path := GetManifestPath(rootPath, key)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	paths, err := ListManifests(rootPath)
+	if err != nil {
+		return "", err
+	}
+
+	number, _ := strconv.Atoi(key)
+	for _, path = range paths {
+		m, err := ReadManifest(path)
+		if err != nil {
+			continue
+		}
+
+		if m.Url == key || m.Title == key || (number > 0 && m.Number == number) {
+			return path, nil
+		}
+	}
+
+	return "", errors.Errorf("comic:%v not found under %v", key, filepath.Clean(rootPath))
+}
+
+type LibraryEntry struct {
+	Title              string `json:"title"`
+	EnTitle            string `json:"pinyin_title"`
+	Number             int    `json:"number"`
+	Url                string `json:"url"`
+	Category           string `json:"category"`
+	LastModifyTime     string `json:"last_modified"`
+	SiteChapters       int    `json:"site_chapters"`
+	DownloadedChapters int    `json:"downloaded_chapters"`
+	Images             int    `json:"images"`
+	Size               int64  `json:"size"`
+}
+
+func ScanLibrary(rootPath string) ([]*LibraryEntry, error) {
+	paths, err := ListManifests(rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*LibraryEntry, 0, len(paths))
+	for _, path := range paths {
+		c, err := LoadManifest(path)
+		if err != nil {
+			log.Errorf("manifest:%v, load failed, err:%v", path, err)
+			continue
+		}
+
+		entries = append(entries, c.libraryEntry())
+	}
+
+	return entries, nil
+}
+
+// libraryEntry 按 journal 统计已下载的图片，和 serve 一样只比对地址和大小
+func (c *Comics) libraryEntry() *LibraryEntry {
+	entry := &LibraryEntry{
+		Title:          c.Title,
+		EnTitle:        c.EnTitle,
+		Number:         c.Number,
+		Url:            c.MainUrl,
+		Category:       c.Category,
+		LastModifyTime: c.LastModifyTime,
+		SiteChapters:   c.lastSiteChapters(),
+	}
+
+	journal, err := ReadJournal(c.getJournalPath())
+	if err != nil {
+		log.Errorf("comic:%v, read journal failed, err:%v", c.EnTitle, err)
+	}
+
+	for _, chapter := range c.Chapters {
+		exist := 0
+		for _, image := range chapter.Images {
+			imagePath, err := c.getImageDataPath(image)
+			if err != nil {
+				continue
+			}
+
+			key := imageJobKey(image)
+			if !journal.IsDoneQuick(key, image.Url, imagePath) {
+				continue
+			}
+
+			exist++
+			if e, ok := journal.Get(key); ok {
+				entry.Size += e.Size
+			}
+		}
+
+		entry.Images += exist
+		if exist > 0 && exist == len(chapter.Images) {
+			entry.DownloadedChapters++
+		}
+	}
+
+	return entry
+}
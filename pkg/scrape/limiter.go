@@ -0,0 +1,78 @@
+package scrape
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+type hostLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     map[string]time.Time
+}
+
+func newHostLimiter(rate float64) *hostLimiter {
+	l := &hostLimiter{
+		next: make(map[string]time.Time),
+	}
+
+	if rate > 0 {
+		l.interval = time.Duration(float64(time.Second) / rate)
+	}
+
+	return l
+}
+
+func (l *hostLimiter) Wait(ctx context.Context, rawUrl string) error {
+	if l == nil || l.interval <= 0 {
+		return nil
+	}
+
+	host := rawUrl
+	if u, err := url.Parse(rawUrl); err == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next[host] = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(slot))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type limiterKey struct{}
+
+// withLimiter 让请求在真正发往网络前按域名限速，直接由缓存返回的请求不占用配额
+func withLimiter(ctx context.Context, l *hostLimiter) context.Context {
+	return context.WithValue(ctx, limiterKey{}, l)
+}
+
+type limitTransport struct {
+	base http.RoundTripper
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if l, ok := req.Context().Value(limiterKey{}).(*hostLimiter); ok {
+		if err := l.Wait(req.Context(), req.URL.String()); err != nil {
+			return nil, err
+		}
+	}
+
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,181 @@
+package scrape
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ManifestVersion     = 1
+	DefaultManifestName = "comic.json"
+)
+
+type Manifest struct {
+	Version        int        `json:"version"`
+	Title          string     `json:"title"`
+	EnTitle        string     `json:"pinyin_title"`
+	Number         int        `json:"number"`
+	Url            string     `json:"url"`
+	CoverUrl       string     `json:"cover_url"`
+	Desc           string     `json:"description"`
+	LastModifyTime string     `json:"last_modified"`
+	Category       string     `json:"category"`
+	Subtitle       string     `json:"subtitle"`
+	Layout         string     `json:"layout"`
+	SiteChapters   int        `json:"site_chapters,omitempty"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	Chapters       []*Chapter `json:"chapters"`
+}
+
+func (c *Comics) Manifest() *Manifest {
+	return &Manifest{
+		Version:        ManifestVersion,
+		Title:          c.Title,
+		EnTitle:        c.EnTitle,
+		Number:         c.Number,
+		Url:            c.MainUrl,
+		CoverUrl:       c.CoverUrl,
+		Desc:           c.Desc,
+		LastModifyTime: c.LastModifyTime,
+		Category:       c.Category,
+		Subtitle:       c.Subtitle,
+		Layout:         c.Layout,
+		SiteChapters:   c.SiteChapters,
+		UpdatedAt:      time.Now(),
+		Chapters:       c.Chapters,
+	}
+}
+
+func (c *Comics) WriteMetadata() error {
+	metaPath := c.getMetadataPath()
+	log.Debugf("metadata path:%v", metaPath)
+
+	buf := bytes.Buffer{}
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+
+	m := c.Manifest()
+	m.Chapters = c.mergeChapters(metaPath)
+
+	if err := enc.Encode(m); err != nil {
+		return errors.Wrap(err, "marshal manifest failed")
+	}
+
+	if err := c.writeFile(metaPath, buf.Bytes()); err != nil {
+		log.Errorf("write metadata failed, err:%v", err)
+		return err
+	}
+
+	c.updateSearchIndex()
+
+	log.Debugf("write metadata success.")
+	return nil
+}
+
+func (c *Comics) mergeChapters(metaPath string) []*Chapter {
+	old, err := ReadManifest(metaPath)
+	if err != nil {
+		return c.Chapters
+	}
+
+	merged := make(map[int]*Chapter, len(old.Chapters)+len(c.Chapters))
+	for _, chapter := range old.Chapters {
+		merged[chapter.Index] = chapter
+	}
+
+	for _, chapter := range c.Chapters {
+		if prev, ok := merged[chapter.Index]; ok && len(chapter.Images) == 0 && len(prev.Images) > 0 {
+			continue
+		}
+
+		merged[chapter.Index] = chapter
+	}
+
+	chapters := make([]*Chapter, 0, len(merged))
+	for _, chapter := range merged {
+		chapters = append(chapters, chapter)
+	}
+
+	sort.Slice(chapters, func(i, j int) bool {
+		return chapters[i].Index < chapters[j].Index
+	})
+
+	return chapters
+}
+
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := new(Manifest)
+	if err = json.Unmarshal(data, m); err != nil {
+		return nil, errors.Wrap(err, "unmarshal manifest failed")
+	}
+
+	if m.Version <= 0 || m.Version > ManifestVersion {
+		return nil, errors.Errorf("unsupported manifest version:%v", m.Version)
+	}
+
+	return m, nil
+}
+
+func LoadManifest(path string) (*Comics, error) {
+	c := New("")
+	if err := c.RestoreManifest(path); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *Comics) RestoreManifest(path string) error {
+	m, err := ReadManifest(path)
+	if err != nil {
+		return err
+	}
+
+	c.MainUrl = m.Url
+	c.RootPath = filepath.Dir(filepath.Dir(filepath.Dir(path)))
+	c.Number = m.Number
+	c.Title = m.Title
+	c.EnTitle = m.EnTitle
+	c.CoverUrl = m.CoverUrl
+	c.Desc = m.Desc
+	c.LastModifyTime = m.LastModifyTime
+	c.Category = m.Category
+	c.Subtitle = m.Subtitle
+	c.SiteChapters = m.SiteChapters
+	c.PageUrls = c.PageUrls[:0]
+	c.ImageUrls = c.ImageUrls[:0]
+	c.Chapters = c.Chapters[:0]
+
+	if m.Layout != "" {
+		c.Layout = m.Layout
+	}
+
+	for _, chapter := range m.Chapters {
+		for _, image := range chapter.Images {
+			image.Chapter = chapter.Index
+			c.ImageUrls = append(c.ImageUrls, image.Url)
+		}
+
+		c.PageUrls = append(c.PageUrls, chapter.PageUrl)
+		c.Chapters = append(c.Chapters, chapter)
+	}
+
+	return nil
+}
+
+func GetManifestPath(rootPath, enTitle string) string {
+	return filepath.Join(rootPath, enTitle, DefaultMetadataPath, DefaultManifestName)
+}
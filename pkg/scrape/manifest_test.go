@@ -0,0 +1,125 @@
+package scrape
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newManifestComics(root string) *Comics {
+	c := New("https://www.san499.com/101344455.html")
+	c.RootPath = root
+	c.Number = 101344455
+	c.Title = "致命坏男人"
+	c.EnTitle = "zhiminghuainanren"
+	c.CoverUrl = "https://www.san499.com/cover.jpg"
+	c.Desc = "<b>描述</b> & 简介"
+	c.LastModifyTime = "2024-01-02"
+	c.Category = "韩国"
+	c.Subtitle = "第二季"
+	c.Layout = LayoutCdn
+	c.SiteChapters = 80
+	c.Chapters = []*Chapter{
+		{Index: 1, PageUrl: c.MainUrl, Images: []*Image{
+			{Url: "https://img.san499.com/1/001.jpg", Chapter: 1, Index: 1, Width: 800, Height: 1200},
+			{Url: "https://img.san499.com/1/002.webp", Chapter: 1, Index: 2},
+		}},
+		{Index: 2, PageUrl: "https://www.san499.com/101344455.html/2", Images: []*Image{
+			{Url: "https://img.san499.com/2/001.png", Chapter: 2, Index: 1},
+		}},
+	}
+
+	return c
+}
+
+func TestManifestRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	c := newManifestComics(root)
+
+	if err := c.WriteMetadata(); err != nil {
+		t.Fatalf("WriteMetadata() err = %v", err)
+	}
+
+	got, err := LoadManifest(GetManifestPath(root, c.EnTitle))
+	if err != nil {
+		t.Fatalf("LoadManifest() err = %v", err)
+	}
+
+	if got.MainUrl != c.MainUrl || got.RootPath != root || got.Number != c.Number || got.Title != c.Title ||
+		got.EnTitle != c.EnTitle || got.CoverUrl != c.CoverUrl || got.Desc != c.Desc ||
+		got.LastModifyTime != c.LastModifyTime || got.Category != c.Category || got.Subtitle != c.Subtitle ||
+		got.Layout != c.Layout || got.SiteChapters != c.SiteChapters {
+		t.Errorf("LoadManifest() = %+v, want %+v", got, c)
+	}
+
+	if !reflect.DeepEqual(got.Chapters, c.Chapters) {
+		t.Errorf("LoadManifest() chapters differ")
+	}
+
+	wantPages := []string{c.Chapters[0].PageUrl, c.Chapters[1].PageUrl}
+	if !reflect.DeepEqual(got.PageUrls, wantPages) {
+		t.Errorf("LoadManifest() page urls = %v, want %v", got.PageUrls, wantPages)
+	}
+
+	if len(got.ImageUrls) != 3 || got.ImageUrls[2] != "https://img.san499.com/2/001.png" {
+		t.Errorf("LoadManifest() image urls = %v", got.ImageUrls)
+	}
+}
+
+func TestManifestMergeChapters(t *testing.T) {
+	root := t.TempDir()
+	c := newManifestComics(root)
+	if err := c.WriteMetadata(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 只选了第 2 话并且没解析出图片，第 1 话和第 2 话的图片都要保留
+	c.Chapters = []*Chapter{{Index: 2, PageUrl: "https://www.san499.com/101344455.html/2"}, {Index: 3, PageUrl: "p3"}}
+	if err := c.WriteMetadata(); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := ReadManifest(GetManifestPath(root, c.EnTitle))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	images := make([]int, 0, len(m.Chapters))
+	for _, chapter := range m.Chapters {
+		images = append(images, len(chapter.Images))
+	}
+
+	if !reflect.DeepEqual(images, []int{2, 1, 0}) {
+		t.Errorf("merged chapter images = %v, want [2 1 0]", images)
+	}
+}
+
+func TestReadManifestVersion(t *testing.T) {
+	tests := []struct {
+		data    string
+		wantErr string
+	}{
+		{`{"version":1,"title":"a"}`, ""},
+		{`{"title":"a"}`, "unsupported manifest version"},
+		{`{"version":2,"title":"a"}`, "unsupported manifest version"},
+		{`{"version":`, "unmarshal manifest failed"},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), DefaultManifestName)
+		if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := ReadManifest(path)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("ReadManifest(%s) err = %v", tt.data, err)
+		}
+
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("ReadManifest(%s) err = %v, want %q", tt.data, err, tt.wantErr)
+		}
+	}
+}
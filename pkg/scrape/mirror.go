@@ -0,0 +1,122 @@
+package scrape
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type mirrorSet struct {
+	mu     sync.Mutex
+	hosts  []string
+	known  map[string]bool
+	active int
+}
+
+func newMirrorSet(hosts []string) *mirrorSet {
+	m := &mirrorSet{known: make(map[string]bool, len(hosts))}
+	for _, host := range hosts {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if host == "" || m.known[host] {
+			continue
+		}
+
+		m.hosts = append(m.hosts, host)
+		m.known[host] = true
+	}
+
+	return m
+}
+
+func (m *mirrorSet) contains(host string) bool {
+	return m != nil && m.known[strings.ToLower(host)]
+}
+
+// canonical 把镜像域名统一换成首选域名，保证页面路径、日志和元数据不随域名变化
+func (m *mirrorSet) canonical(rawUrl string) string {
+	if m == nil || len(m.hosts) == 0 {
+		return rawUrl
+	}
+
+	u, err := url.Parse(rawUrl)
+	if err != nil || !m.contains(u.Host) {
+		return rawUrl
+	}
+
+	u.Host = m.hosts[0]
+	return u.String()
+}
+
+func (m *mirrorSet) candidates(rawUrl string) []string {
+	u, err := url.Parse(rawUrl)
+	if err != nil || m == nil || !m.contains(u.Host) {
+		return []string{rawUrl}
+	}
+
+	m.mu.Lock()
+	active := m.active
+	m.mu.Unlock()
+
+	urls := make([]string, 0, len(m.hosts))
+	for i := range m.hosts {
+		mirror := *u
+		mirror.Host = m.hosts[(active+i)%len(m.hosts)]
+		urls = append(urls, mirror.String())
+	}
+
+	return urls
+}
+
+func (m *mirrorSet) activate(rawUrl string) {
+	u, err := url.Parse(rawUrl)
+	if err != nil || m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, host := range m.hosts {
+		if host == strings.ToLower(u.Host) && m.active != i {
+			log.Infof("switch to mirror:%v", host)
+			m.active = i
+			return
+		}
+	}
+}
+
+// isFailoverError 判断是否该换镜像，比 IsRetryable 宽：域名被封、解析不到、连不上或证书不对时
+// 重试同一个域名没有意义，但换个镜像可能就好了
+func isFailoverError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var de *DownloadError
+	if errors.As(err, &de) {
+		return de.Kind == ErrServerError
+	}
+
+	var (
+		opErr      *net.OpError
+		dnsErr     *net.DNSError
+		verifyErr  *tls.CertificateVerificationError
+		authErr    x509.UnknownAuthorityError
+		hostErr    x509.HostnameError
+		invalidErr x509.CertificateInvalidError
+	)
+
+	if errors.As(err, &opErr) || errors.As(err, &dnsErr) || errors.As(err, &verifyErr) ||
+		errors.As(err, &authErr) || errors.As(err, &hostErr) || errors.As(err, &invalidErr) {
+		return true
+	}
+
+	return IsRetryable(err)
+}
@@ -0,0 +1,208 @@
+package scrape
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+func (c *Comics) exportPdf() error {
+	failed := make([]string, 0, 4)
+	volumes := 0
+
+	size := c.VolumeSize
+	if size <= 0 {
+		size = 1
+	}
+
+	for start := 0; start < len(c.Chapters); start += size {
+		end := start + size
+		if end > len(c.Chapters) {
+			end = len(c.Chapters)
+		}
+
+		chapters := c.Chapters[start:end]
+		name := fmt.Sprintf("%s-%s.pdf", c.EnTitle, chapters[0].Name())
+		if len(chapters) > 1 {
+			name = fmt.Sprintf("%s-%s-%s.pdf", c.EnTitle, chapters[0].Name(), chapters[len(chapters)-1].Name())
+		}
+
+		volumes++
+		images, err := c.getVolumeImages(chapters)
+		if err != nil {
+			log.Errorf("pdf:%v, skip, err:%v", name, err)
+			failed = append(failed, fmt.Sprintf("%v: %v", name, err))
+			continue
+		}
+
+		pdfPath := c.getExportDataPath(name)
+		if err := writePdf(pdfPath, c.Title, images); err != nil {
+			log.Errorf("pdf:%v, write failed, err:%v", pdfPath, err)
+			failed = append(failed, fmt.Sprintf("%v: %v", name, err))
+			continue
+		}
+
+		log.Debugf("write pdf:%v success", pdfPath)
+	}
+
+	return exportFailed(ExportPdf, volumes, failed)
+}
+
+// getVolumeImages 一卷里任何一话缺图，整卷都不导出
+func (c *Comics) getVolumeImages(chapters []*Chapter) ([]*exportImage, error) {
+	images := make([]*exportImage, 0, 64)
+	for _, chapter := range chapters {
+		chapterImages, err := c.getExportImages(chapter)
+		if err != nil {
+			return nil, errors.Wrapf(err, "chapter %v", chapter.Index)
+		}
+
+		images = append(images, chapterImages...)
+	}
+
+	return images, nil
+}
+
+type pdfWriter struct {
+	w       *bufio.Writer
+	offset  int64
+	offsets []int64
+}
+
+func (p *pdfWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.offset += int64(n)
+	return n, err
+}
+
+func (p *pdfWriter) alloc() int {
+	p.offsets = append(p.offsets, 0)
+	return len(p.offsets)
+}
+
+func (p *pdfWriter) object(num int, format string, args ...interface{}) {
+	p.offsets[num-1] = p.offset
+	fmt.Fprintf(p, "%d 0 obj\n", num)
+	fmt.Fprintf(p, format, args...)
+	fmt.Fprint(p, "\nendobj\n")
+}
+
+func (p *pdfWriter) stream(num int, dict string, data []byte) {
+	p.offsets[num-1] = p.offset
+	fmt.Fprintf(p, "%d 0 obj\n<< %s /Length %d >>\nstream\n", num, dict, len(data))
+	p.Write(data)
+	fmt.Fprint(p, "\nendstream\nendobj\n")
+}
+
+// writePdf 先写临时文件再改名，任何一页读取失败整个文件都算失败，不会留下缺页或半截的 pdf
+func writePdf(pdfPath, title string, images []*exportImage) error {
+	fd, err := createTempFile(pdfPath)
+	if err != nil {
+		return err
+	}
+
+	return commitTempFile(fd, pdfPath, func(w io.Writer) error {
+		return writePdfPages(w, title, images)
+	})
+}
+
+func writePdfPages(fd io.Writer, title string, images []*exportImage) error {
+	p := &pdfWriter{w: bufio.NewWriter(fd)}
+	fmt.Fprint(p, "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	catalog, pages, info := p.alloc(), p.alloc(), p.alloc()
+	kids := make([]string, 0, len(images))
+
+	for _, image := range images {
+		data, pixel, err := pdfImageData(image.Path)
+		if err != nil {
+			return errors.Wrapf(err, "image:%v, read for pdf failed", image.Path)
+		}
+
+		width, height := image.Size()
+		if width <= 0 || height <= 0 {
+			return errors.Errorf("image:%v, unknown size", image.Path)
+		}
+
+		colorSpace := "/DeviceRGB"
+		if pixel.ColorModel == color.GrayModel {
+			colorSpace = "/DeviceGray"
+		}
+
+		xobject, content, page := p.alloc(), p.alloc(), p.alloc()
+
+		p.stream(xobject, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter /DCTDecode",
+			pixel.Width, pixel.Height, colorSpace), data)
+		p.stream(content, "", []byte(fmt.Sprintf("q %d 0 0 %d 0 0 cm /Im0 Do Q", width, height)))
+		p.object(page, "<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] /Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>",
+			pages, width, height, xobject, content)
+
+		kids = append(kids, fmt.Sprintf("%d 0 R", page))
+	}
+
+	if len(kids) == 0 {
+		return errors.New("no valid image")
+	}
+
+	p.object(pages, "<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+	p.object(catalog, "<< /Type /Catalog /Pages %d 0 R >>", pages)
+	p.object(info, "<< /Title %s /Producer (sansi) >>", pdfText(title))
+
+	xref := p.offset
+	fmt.Fprintf(p, "xref\n0 %d\n0000000000 65535 f \n", len(p.offsets)+1)
+	for _, offset := range p.offsets {
+		fmt.Fprintf(p, "%010d 00000 n \n", offset)
+	}
+
+	fmt.Fprintf(p, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets)+1, catalog, info, xref)
+	return p.w.Flush()
+}
+
+func pdfImageData(path string) ([]byte, image.Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, image.Config{}, err
+	}
+
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err == nil && (cfg.ColorModel == color.GrayModel || cfg.ColorModel == color.YCbCrModel) {
+		return data, cfg, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, image.Config{}, errors.Wrap(err, "decode image failed")
+	}
+
+	buf := bytes.Buffer{}
+	if err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, image.Config{}, errors.Wrap(err, "encode jpeg failed")
+	}
+
+	// 灰度图重新编码后是单通道的 jpeg，颜色空间以编码结果为准
+	if cfg, err = jpeg.DecodeConfig(bytes.NewReader(buf.Bytes())); err != nil {
+		return nil, image.Config{}, errors.Wrap(err, "decode encoded jpeg failed")
+	}
+
+	return buf.Bytes(), cfg, nil
+}
+
+func pdfText(s string) string {
+	buf := bytes.NewBufferString("<FEFF")
+	for _, r := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(buf, "%04X", r)
+	}
+
+	buf.WriteString(">")
+	return buf.String()
+}
@@ -0,0 +1,89 @@
+package scrape
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestWritePdfColorSpace(t *testing.T) {
+	dir := t.TempDir()
+
+	writePng := func(name string, img image.Image) *exportImage {
+		path := filepath.Join(dir, name)
+		buf := bytes.Buffer{}
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		return &exportImage{Image: &Image{Url: "https://img.san499.com/" + name}, Path: path}
+	}
+
+	gray := image.NewGray(image.Rect(0, 0, 16, 24))
+	rgba := image.NewRGBA(image.Rect(0, 0, 16, 24))
+	for y := 0; y < 24; y++ {
+		for x := 0; x < 16; x++ {
+			gray.SetGray(x, y, color.Gray{Y: uint8(x * 16)})
+			rgba.SetRGBA(x, y, color.RGBA{R: uint8(x * 16), G: 80, B: uint8(y * 10), A: 255})
+		}
+	}
+
+	tests := []struct {
+		name       string
+		image      image.Image
+		colorSpace string
+		components int
+	}{
+		{"gray.png", gray, "/DeviceGray", 1},
+		{"rgb.png", rgba, "/DeviceRGB", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pdfPath := filepath.Join(dir, tt.name+".pdf")
+			if err := writePdf(pdfPath, "测试", []*exportImage{writePng(tt.name, tt.image)}); err != nil {
+				t.Fatalf("writePdf() err = %v", err)
+			}
+
+			data, err := os.ReadFile(pdfPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			m := regexp.MustCompile(`/ColorSpace (/\w+) /BitsPerComponent 8 /Filter /DCTDecode /Length (\d+) >>\nstream\n`).FindSubmatchIndex(data)
+			if m == nil {
+				t.Fatalf("no image xobject in pdf")
+			}
+
+			if got := string(data[m[2]:m[3]]); got != tt.colorSpace {
+				t.Errorf("color space = %v, want %v", got, tt.colorSpace)
+			}
+
+			length, _ := strconv.Atoi(string(data[m[4]:m[5]]))
+			cfg, err := jpeg.DecodeConfig(bytes.NewReader(data[m[1] : m[1]+length]))
+			if err != nil {
+				t.Fatalf("decode embedded jpeg err = %v", err)
+			}
+
+			components := 3
+			if cfg.ColorModel == color.GrayModel {
+				components = 1
+			}
+
+			if components != tt.components || cfg.Width != 16 || cfg.Height != 24 {
+				t.Errorf("embedded jpeg = %v components %vx%v, want %v components 16x24", components, cfg.Width, cfg.Height, tt.components)
+			}
+		})
+	}
+}
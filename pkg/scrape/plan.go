@@ -0,0 +1,160 @@
+package scrape
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type ChapterPlan struct {
+	Index        int    `json:"index"`
+	PageUrl      string `json:"page_url"`
+	Images       int    `json:"images"`
+	OnDisk       int    `json:"on_disk"`
+	OnDiskSize   int64  `json:"on_disk_size"`
+	Missing      int    `json:"missing"`
+	DownloadSize int64  `json:"download_size"`
+	UnknownSize  int    `json:"unknown_size"`
+}
+
+type Plan struct {
+	Title        string         `json:"title"`
+	EnTitle      string         `json:"pinyin_title"`
+	Number       int            `json:"number"`
+	Url          string         `json:"url"`
+	Layout       string         `json:"layout"`
+	Chapters     []*ChapterPlan `json:"chapters"`
+	Images       int            `json:"images"`
+	OnDisk       int            `json:"on_disk"`
+	OnDiskSize   int64          `json:"on_disk_size"`
+	Missing      int            `json:"missing"`
+	DownloadSize int64          `json:"download_size"`
+	UnknownSize  int            `json:"unknown_size"`
+}
+
+// Plan 只抓取主页和章节页，统计要下载的图片，不写任何文件；
+// 本地没有的图片用 HEAD 请求估算大小，拿不到长度的计入 UnknownSize
+func (c *Comics) Plan() (*Plan, error) {
+	c.dryRun = true
+
+	if err := c.Validity(); err != nil {
+		return nil, err
+	}
+
+	if err := c.GetMainContent(); err != nil {
+		return nil, err
+	}
+
+	if err := c.ParseMainBasicInfo(); err != nil {
+		return nil, err
+	}
+
+	c.useExistingLayout()
+
+	if err := c.ParseMainPageUrls(); err != nil {
+		return nil, err
+	}
+
+	if err := c.GetPageUrlsContent(); err != nil {
+		return nil, err
+	}
+
+	if err := c.GetImageUrls(); err != nil {
+		return nil, err
+	}
+
+	// 只读加载 journal 判断图片是否已完整下载，dry run 不写 journal
+	journal, err := ReadJournal(c.getJournalPath())
+	if err != nil {
+		return nil, errors.Wrap(err, "read journal failed")
+	}
+
+	c.journal = journal
+
+	p := &Plan{
+		Title:    c.Title,
+		EnTitle:  c.EnTitle,
+		Number:   c.Number,
+		Url:      c.MainUrl,
+		Layout:   c.Layout,
+		Chapters: make([]*ChapterPlan, 0, len(c.Chapters)),
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		tasks = make(chan *Image, c.Concurrency)
+		plans = make(map[int]*ChapterPlan, len(c.Chapters))
+	)
+
+	for _, chapter := range c.Chapters {
+		cp := &ChapterPlan{Index: chapter.Index, PageUrl: chapter.PageUrl, Images: len(chapter.Images)}
+		plans[chapter.Index] = cp
+		p.Chapters = append(p.Chapters, cp)
+	}
+
+	for i := 0; i < c.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for image := range tasks {
+				size := c.headImage(image.Url)
+
+				mu.Lock()
+				cp := plans[image.Chapter]
+				if size < 0 {
+					cp.UnknownSize++
+				} else {
+					cp.DownloadSize += size
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, chapter := range c.Chapters {
+		cp := plans[chapter.Index]
+		for _, image := range chapter.Images {
+			imagePath, err := c.getImageDataPath(image)
+			if err == nil && c.journal.IsDone(imageJobKey(image), image.Url, imagePath) {
+				entry, _ := c.journal.Get(imageJobKey(image))
+				cp.OnDisk++
+				cp.OnDiskSize += entry.Size
+				continue
+			}
+
+			cp.Missing++
+			tasks <- image
+		}
+	}
+
+	close(tasks)
+	wg.Wait()
+
+	for _, cp := range p.Chapters {
+		p.Images += cp.Images
+		p.OnDisk += cp.OnDisk
+		p.OnDiskSize += cp.OnDiskSize
+		p.Missing += cp.Missing
+		p.DownloadSize += cp.DownloadSize
+		p.UnknownSize += cp.UnknownSize
+	}
+
+	return p, nil
+}
+
+func (c *Comics) headImage(imageUrl string) (size int64) {
+	err := c.retry.Do(imageUrl, func() (err error) {
+		size, err = HeadImageContext(withLimiter(c.baseContext(), c.limiter), c.client, imageUrl)
+		return err
+	})
+
+	if err != nil {
+		log.Errorf("imageUrl:%v, head failed, err:%v", imageUrl, err)
+		return -1
+	}
+
+	return size
+}
@@ -0,0 +1,118 @@
+package scrape
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+type ProfilePatterns struct {
+	Comic        string `yaml:"comic" json:"comic"`
+	Page         string `yaml:"page" json:"page"`
+	Image        string `yaml:"image" json:"image"`
+	LastModified string `yaml:"last_modified" json:"last_modified"`
+}
+
+type ProfileSelectors struct {
+	Title    string `yaml:"title" json:"title"`
+	Desc     string `yaml:"desc" json:"desc"`
+	Cover    string `yaml:"cover" json:"cover"`
+	Meta     string `yaml:"meta" json:"meta"`
+	Category string `yaml:"category" json:"category"`
+	Subtitle string `yaml:"subtitle" json:"subtitle"`
+	Paging   string `yaml:"paging" json:"paging"`
+	Images   string `yaml:"images" json:"images"`
+
+	ListItem  string `yaml:"list_item" json:"list_item"`
+	ListLink  string `yaml:"list_link" json:"list_link"`
+	ListCover string `yaml:"list_cover" json:"list_cover"`
+	ListNext  string `yaml:"list_next" json:"list_next"`
+}
+
+type Profile struct {
+	Name      string           `yaml:"name" json:"name"`
+	Hosts     []string         `yaml:"hosts" json:"hosts"`
+	Mirrors   []string         `yaml:"mirrors" json:"mirrors"`
+	Patterns  ProfilePatterns  `yaml:"patterns" json:"patterns"`
+	Selectors ProfileSelectors `yaml:"selectors" json:"selectors"`
+
+	comicRe        *regexp.Regexp
+	pageRe         *regexp.Regexp
+	imageRe        *regexp.Regexp
+	lastModifiedRe *regexp.Regexp
+}
+
+func LoadProfile(path string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read profile failed")
+	}
+
+	return ParseProfile(data, strings.ToLower(filepath.Ext(path)) == ".json")
+}
+
+func ParseProfile(data []byte, isJson bool) (*Profile, error) {
+	p := new(Profile)
+
+	var err error
+	if isJson {
+		err = json.Unmarshal(data, p)
+	} else {
+		err = yaml.Unmarshal(data, p)
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshal profile failed")
+	}
+
+	if err = p.compile(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *Profile) compile() error {
+	if p.Name == "" {
+		return errors.New("profile: empty name")
+	}
+
+	if p.Selectors.Title == "" || p.Selectors.Images == "" {
+		return errors.Errorf("profile:%v, title and images selectors are required", p.Name)
+	}
+
+	patterns := []struct {
+		name string
+		expr string
+		re   **regexp.Regexp
+	}{
+		{"comic", p.Patterns.Comic, &p.comicRe},
+		{"page", p.Patterns.Page, &p.pageRe},
+		{"image", p.Patterns.Image, &p.imageRe},
+		{"last_modified", p.Patterns.LastModified, &p.lastModifiedRe},
+	}
+
+	for _, pattern := range patterns {
+		if pattern.expr == "" {
+			continue
+		}
+
+		re, err := regexp.Compile(pattern.expr)
+		if err != nil {
+			return errors.Wrapf(err, "profile:%v, invalid %v pattern", p.Name, pattern.name)
+		}
+
+		*pattern.re = re
+	}
+
+	if p.comicRe == nil || p.comicRe.NumSubexp() < 1 {
+		return errors.Errorf("profile:%v, comic pattern must capture the comic number", p.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package scrape
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseProfile(t *testing.T) {
+	const yamlProfile = `
+name: demo
+hosts: [demo.com]
+patterns:
+  comic: '/comic/(\d+)\.html$'
+  image: '(?i)\.(jpg|png)$'
+selectors:
+  title: 'h1'
+  images: '.content img'
+`
+
+	const jsonProfile = `{
+  "name": "demo",
+  "hosts": ["demo.com"],
+  "patterns": {"comic": "/comic/(\\d+)\\.html$"},
+  "selectors": {"title": "h1", "images": ".content img"}
+}`
+
+	tests := []struct {
+		name    string
+		data    string
+		isJson  bool
+		wantErr string
+	}{
+		{"yaml", yamlProfile, false, ""},
+		{"json", jsonProfile, true, ""},
+		{"yaml as json", yamlProfile, true, "unmarshal profile failed"},
+		{"empty name", strings.Replace(yamlProfile, "name: demo", "name: ''", 1), false, "empty name"},
+		{"no images selector", strings.Replace(yamlProfile, "images: '.content img'", "", 1), false, "selectors are required"},
+		{"invalid image pattern", strings.Replace(yamlProfile, `(?i)\.(jpg|png)$`, `(jpg`, 1), false, "invalid image pattern"},
+		{"no comic capture", strings.Replace(yamlProfile, `/comic/(\d+)\.html$`, `/comic/\d+\.html$`, 1), false, "must capture"},
+		{"no comic pattern", strings.Replace(yamlProfile, `comic: '/comic/(\d+)\.html$'`, "", 1), false, "must capture"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParseProfile([]byte(tt.data), tt.isJson)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseProfile() err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseProfile() err = %v", err)
+			}
+
+			if p.Name != "demo" || len(p.Hosts) != 1 || p.Hosts[0] != "demo.com" {
+				t.Errorf("ParseProfile() = %+v", p)
+			}
+
+			u, _ := url.Parse("https://demo.com/comic/42.html")
+			if num, err := NewProfileAdapter(p).ComicNumber(u); err != nil || num != 42 {
+				t.Errorf("ComicNumber() = %v, %v, want 42", num, err)
+			}
+		})
+	}
+}
+
+func TestFindAdapter(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{"https://www.san499.com/101344455.html", "san499", false},
+		{"https://SANSI03.com/101344455.html", "san499", false},
+		{"https://example.com/101344455.html", "", true},
+		{"not a url", "", true},
+	}
+
+	for _, tt := range tests {
+		a, err := FindAdapter(tt.url)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("FindAdapter(%q) = %v, want error", tt.url, a.Name())
+			}
+			continue
+		}
+
+		if err != nil || a.Name() != tt.want {
+			t.Errorf("FindAdapter(%q) = %v, %v, want %v", tt.url, a, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+//go:build linux
+
+package scrape
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// reflink 用 FICLONE 让 dst 和 src 共享数据块，需要 btrfs、xfs 等支持写时复制的文件系统
+func reflink(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	err = unix.IoctlFileClone(int(out.Fd()), int(in.Fd()))
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		_ = os.Remove(dst)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package scrape
+
+import (
+	"github.com/pkg/errors"
+)
+
+func reflink(src, dst string) error {
+	return errors.New("reflink not supported on this platform")
+}
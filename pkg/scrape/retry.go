@@ -0,0 +1,69 @@
+package scrape
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultRetries       = 3
+	DefaultRetryDelay    = time.Second
+	DefaultMaxRetryDelay = time.Minute
+)
+
+type RetryPolicy struct {
+	Retries   int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+func (p *RetryPolicy) Do(url string, fn func() error) error {
+	return p.DoContext(context.Background(), url, fn)
+}
+
+// DoContext 在等待重试时 ctx 被取消则立即返回
+func (p *RetryPolicy) DoContext(ctx context.Context, url string, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || !IsRetryable(err) || attempt >= p.Retries {
+			return err
+		}
+
+		delay := p.backoff(attempt, err)
+		log.Infof("url:%v, attempt:%v failed, retry after:%v, err:%v", url, attempt+1, delay, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (p *RetryPolicy) backoff(attempt int, err error) time.Duration {
+	maxDelay := p.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = DefaultMaxRetryDelay
+	}
+
+	if d := retryAfter(err); d > 0 {
+		if d > maxDelay {
+			d = maxDelay
+		}
+
+		return d
+	}
+
+	d := p.BaseDelay << attempt
+	if d <= 0 || d > maxDelay {
+		d = maxDelay
+	}
+
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -2,11 +2,13 @@ package scrape
 
 import (
 	"bytes"
+	"context"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
@@ -24,47 +26,91 @@ var (
 )
 
 func init() {
-	log.SetOutput(os.Stdout)
+	// 日志输出到 stderr，stdout 只留给命令结果
+	log.SetOutput(os.Stderr)
 	log.SetFormatter(&log.TextFormatter{})
 	log.SetLevel(log.DebugLevel)
 }
 
 const (
 	DefaultTimeout         = 10 // 秒
+	DefaultConcurrency     = 4
+	DefaultRateLimit       = 2 // 每个域名每秒请求数
 	DefaultRootPath        = "./"
 	DefaultMetadataPath    = "meta"
 	DefaultImageDataPath   = "images"
 	DefaultPageDataPath    = "pages"
 	DefaultContentDataPath = "content"
+	DefaultChapterDataPath = "chapters"
+	DefaultExportDataPath  = "export"
+	DefaultCoverName       = "cover"
+	DefaultLayout          = LayoutChapter
 )
 
 type Comics struct {
 	Debug           bool
 	RootPath        string
 	Timeout         int
+	Concurrency     int
+	RateLimit       float64
+	Layout          string
+	ExportFormat    string
+	VolumeSize      int
 	MainUrl         string
 	Number          int
 	Title           string
 	EnTitle         string
 	Desc            string
 	LastModifyTime  string
+	Category        string
+	Subtitle        string
 	CoverUrl        string
 	PageUrls        []string
 	ImageUrls       []string
+	Chapters        []*Chapter
+	SiteChapters    int // 站点上的章节数，选了部分章节时 Chapters 只有选中的
+	ChapterRange    ChapterRange
 	rootHtmlContent []byte
 	rootDoc         *goquery.Document
 	pageDocs        map[string]*goquery.Document
+	client          *http.Client
+	configErr       error
+	adapter         SiteAdapter
+	mirrorHosts     []string
+	mirrors         *mirrorSet
+	limiter         *hostLimiter
+	retry           *RetryPolicy
+	journal         *Journal
+	dryRun          bool
+	store           *BlobStore
+	layoutSet       bool
+	ctx             context.Context
+	failedPages     int
+	failedImages    int
 }
 
 func New(url string) *Comics {
+	client, _ := NewHttpClient(ClientOptions{Timeout: DefaultTimeout, ReadTimeout: DefaultReadTimeout})
+
 	return &Comics{
-		Debug:     false,
-		MainUrl:   url,
-		RootPath:  DefaultRootPath,
-		Timeout:   DefaultTimeout,
-		PageUrls:  []string{},
-		ImageUrls: []string{},
-		pageDocs:  make(map[string]*goquery.Document),
+		Debug:       false,
+		MainUrl:     url,
+		RootPath:    DefaultRootPath,
+		Timeout:     DefaultTimeout,
+		Concurrency: DefaultConcurrency,
+		RateLimit:   DefaultRateLimit,
+		Layout:      DefaultLayout,
+		PageUrls:    []string{},
+		ImageUrls:   []string{},
+		Chapters:    []*Chapter{},
+		pageDocs:    make(map[string]*goquery.Document),
+		client:      client,
+		limiter:     newHostLimiter(DefaultRateLimit),
+		retry: &RetryPolicy{
+			Retries:   DefaultRetries,
+			BaseDelay: DefaultRetryDelay,
+			MaxDelay:  DefaultMaxRetryDelay,
+		},
 	}
 }
 
@@ -81,10 +127,90 @@ func NewWithConfig(cfg *Config) *Comics {
 		//c.MetadataPath = filepath.Join(cfg.RootPath, DefaultMetadataPath)
 	}
 
+	if cfg.Timeout > 0 {
+		c.Timeout = cfg.Timeout
+	}
+
+	opt := cfg.ClientOptions()
+	opt.Timeout = c.Timeout
+	c.client, c.configErr = NewHttpClient(opt)
+
+	if cfg.Profile != "" && c.configErr == nil {
+		c.adapter, c.configErr = LoadProfileAdapter(cfg.Profile)
+	}
+
+	c.mirrorHosts = cfg.Mirrors
+
+	if cfg.Concurrency > 0 {
+		c.Concurrency = cfg.Concurrency
+	}
+
+	if cfg.RateLimit != 0 {
+		c.RateLimit = cfg.RateLimit
+		c.limiter = newHostLimiter(cfg.RateLimit)
+	}
+
+	if cfg.Layout != "" {
+		c.Layout = cfg.Layout
+		c.layoutSet = true
+	}
+
+	if cfg.Retries > 0 {
+		c.retry.Retries = cfg.Retries
+	} else if cfg.Retries < 0 {
+		c.retry.Retries = 0
+	}
+
+	if cfg.RetryDelay > 0 {
+		c.retry.BaseDelay = cfg.RetryDelay
+	}
+
+	c.ExportFormat = cfg.Export
+	c.VolumeSize = cfg.VolumeSize
+
+	if cfg.Store && c.configErr == nil {
+		c.store, c.configErr = OpenStore(GetStorePath(c.RootPath), cfg.StoreLink)
+	}
+
+	if cfg.Chapters != "" && c.configErr == nil {
+		c.ChapterRange, c.configErr = ParseChapterRange(cfg.Chapters)
+	}
+
 	return c
 }
 
-func (c *Comics) Scrape() error {
+func (c *Comics) Scrape() (err error) {
+	defer c.Close()
+
+	record := &ScrapeRecord{StartedAt: time.Now()}
+	defer func() {
+		record.FinishedAt = time.Now()
+		record.Error = err
+		c.recordLibrary(record)
+	}()
+
+	if err = c.Prepare(); err != nil {
+		return err
+	}
+
+	if err = c.GetCoverContent(); err != nil {
+		log.Errorf("get cover content failed, err:%v", err)
+	}
+
+	if err = c.GetImagesContent(); err != nil {
+		return err
+	}
+
+	if c.ExportFormat != "" {
+		if err = c.Export(c.ExportFormat); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Comics) Prepare() error {
 	if err := c.Validity(); err != nil {
 		log.Error(err)
 		return err
@@ -98,6 +224,11 @@ func (c *Comics) Scrape() error {
 		return err
 	}
 
+	c.useExistingLayout()
+	if err := c.openJournal(); err != nil {
+		return err
+	}
+
 	if err := c.ParseMainPageUrls(); err != nil {
 		return err
 	}
@@ -110,203 +241,220 @@ func (c *Comics) Scrape() error {
 		return err
 	}
 
-	if err := c.GetImagesContent(); err != nil {
+	if err := c.WriteMetadata(); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (c *Comics) Validity() error {
-	if c.MainUrl == "" {
-		return errors.New("empty main url")
-	}
+// FailedPages 和 FailedImages 是最近一次抓取中失败的章节页和图片数，失败只记日志不会让 Scrape 返回错误
+func (c *Comics) FailedPages() int {
+	return c.failedPages
+}
 
-	u, err := url.ParseRequestURI(c.MainUrl)
-	if err != nil {
-		return errors.Wrap(err, "parse main url failed")
-	}
+func (c *Comics) FailedImages() int {
+	return c.failedImages
+}
 
-	dir, file := filepath.Split(u.Path)
-	log.Debugf("dir:%v, file:%v", dir, file)
+func (c *Comics) SetHttpClient(client *http.Client) {
+	c.client = client
+}
 
-	if !strings.HasSuffix(file, ".html") {
-		return errors.New("no html suffix")
-	}
+// SetContext 取消 ctx 时正在进行的下载会中止，还没开始的图片不再下载
+func (c *Comics) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
 
-	pos := strings.LastIndex(file, ".html")
-	num, err := strconv.Atoi(file[:pos])
-	if err != nil {
-		return errors.Wrap(err, "invalid comic number")
+func (c *Comics) baseContext() context.Context {
+	if c.ctx == nil {
+		return context.Background()
 	}
 
-	c.Number = num
-	log.Debugf("mainPage:%v, verified success", c.MainUrl)
-	return nil
+	return c.ctx
 }
 
-func (c *Comics) GetMainContent() error {
-	var (
-		err error
-	)
+func (c *Comics) Close() error {
+	if c.journal == nil {
+		return nil
+	}
 
-	c.rootHtmlContent, err = DownloadPage(c.MainUrl, c.Debug)
-	if err != nil {
-		return errors.Wrap(err, "download main page failed")
+	err := c.journal.Close()
+	c.journal = nil
+	return err
+}
+
+func (c *Comics) openJournal() error {
+	if c.journal != nil {
+		return nil
 	}
 
-	c.rootDoc, err = goquery.NewDocumentFromReader(bytes.NewReader(c.rootHtmlContent))
+	journal, err := OpenJournal(c.getJournalPath())
 	if err != nil {
-		return errors.Wrap(err, "parse main page html failed")
+		return errors.Wrap(err, "open journal failed")
 	}
 
-	log.Debug("download main page success")
+	c.journal = journal
 	return nil
 }
 
-func (c *Comics) ParseMainBasicInfo() error {
-	if err := c.parseTitle(); err != nil {
-		return err
+func (c *Comics) Validity() error {
+	if c.MainUrl == "" {
+		return errors.New("empty main url")
 	}
 
-	if err := c.parseDesc(); err != nil {
-		return err
+	if c.configErr != nil {
+		return errors.Wrap(c.configErr, "invalid config")
 	}
 
-	if err := c.parseCoverUrl(); err != nil {
-		return err
+	if !IsValidLayout(c.Layout) {
+		return errors.Errorf("invalid layout:%v", c.Layout)
+	}
+
+	if c.ExportFormat != "" && !IsValidExportFormat(c.ExportFormat) {
+		return errors.Errorf("unsupported export format:%v", c.ExportFormat)
+	}
+
+	u, err := url.ParseRequestURI(c.MainUrl)
+	if err != nil {
+		return errors.Wrap(err, "parse main url failed")
 	}
 
-	if err := c.parseLastModifyTime(); err != nil {
+	if err = c.initAdapter(c.MainUrl); err != nil {
 		return err
 	}
 
-	if err := c.WriteMetadata(); err != nil {
+	num, err := c.adapter.ComicNumber(u)
+	if err != nil {
 		return err
 	}
 
+	c.MainUrl = c.mirrors.canonical(c.MainUrl)
+
+	c.Number = num
+	log.Debugf("mainPage:%v, verified success", c.MainUrl)
 	return nil
 }
 
-func (c *Comics) parseTitle() error {
-	c.rootDoc.Find(".container .content-wrap .content .article-header .article-title a").Each(func(i int, s *goquery.Selection) {
-		c.Title = s.Text()
-		c.Title = strings.Trim(c.Title, " \n\t\r")
-		c.EnTitle = ParseCnToEn(c.Title)
-	})
-
-	if c.Title == "" {
-		return errors.New("no title")
+func (c *Comics) initAdapter(rawUrl string) (err error) {
+	if c.adapter == nil {
+		c.adapter, err = FindAdapter(rawUrl)
+		if err != nil {
+			return err
+		}
 	}
 
-	log.Debugf("title:%v, enTitle:%v", c.Title, c.EnTitle)
-	return nil
-}
-
-func (c *Comics) parseDesc() error {
-	c.rootDoc.Find(".container .content-wrap .content .article-header .dis").Each(func(i int, s *goquery.Selection) {
-		c.Desc = s.Text()
-		c.Desc = strings.Trim(c.Desc, " \n\t\r")
-	})
+	mirrorHosts := c.mirrorHosts
+	if mp, ok := c.adapter.(MirrorProvider); ok && len(mirrorHosts) == 0 {
+		mirrorHosts = mp.Mirrors()
+	}
 
-	log.Debugf("desc:%v", c.Desc)
+	c.mirrors = newMirrorSet(mirrorHosts)
 	return nil
 }
 
-func (c *Comics) parseCoverUrl() error {
-	c.rootDoc.Find(".container .content-wrap .content .article-header .c-img img").Each(func(i int, s *goquery.Selection) {
-		src, exist := s.Attr("src")
-		if !exist {
-			log.Info("no src attr")
-			return
-		}
+func (c *Comics) GetMainContent() error {
+	var (
+		err error
+	)
 
-		c.CoverUrl = src
-	})
+	c.rootHtmlContent, err = c.downloadPage(c.MainUrl, DefaultMainPageTTL)
+	if err != nil {
+		return errors.Wrap(err, "download main page failed")
+	}
 
-	if c.CoverUrl == "" {
-		return errors.New("no cover url")
+	c.rootDoc, err = goquery.NewDocumentFromReader(bytes.NewReader(c.rootHtmlContent))
+	if err != nil {
+		return errors.Wrap(err, "parse main page html failed")
 	}
 
-	log.Debugf("cover url:%v", c.CoverUrl)
+	log.Debug("download main page success")
 	return nil
 }
 
-func (c *Comics) parseLastModifyTime() error {
-	c.rootDoc.Find(".container .content-wrap .content .article-header .article-meta li").Each(func(i int, s *goquery.Selection) {
-		text := s.Text()
-		text = strings.Trim(text, " \n\t\r")
+func (c *Comics) ParseMainBasicInfo() error {
+	info, err := c.adapter.ParseInfo(c.rootDoc)
+	if err != nil {
+		return err
+	}
 
-		items := strings.Split(text, ":")
-		if len(items) != 2 {
-			log.Info("invalid format")
-			return
-		}
+	c.Title = info.Title
+	c.EnTitle = ParseCnToEn(c.Title)
+	c.Desc = info.Desc
+	c.CoverUrl = info.CoverUrl
+	c.LastModifyTime = info.LastModifyTime
+	c.Category = info.Category
+	c.Subtitle = info.Subtitle
 
-		if _, err := time.ParseInLocation("2006-01-02", items[1], time.Local); err != nil {
-			log.Info("no include yyyy-mm-dd time")
-			return
-		}
+	log.Debugf("title:%v, enTitle:%v, cover url:%v, lastModifyTime:%v, category:%v, subtitle:%v",
+		c.Title, c.EnTitle, c.CoverUrl, c.LastModifyTime, c.Category, c.Subtitle)
+	return nil
+}
 
-		c.LastModifyTime = items[1]
-	})
+// useExistingLayout 没有明确指定布局时沿用已下载漫画的布局，避免默认布局变了之后整本重新下载
+func (c *Comics) useExistingLayout() {
+	if c.layoutSet {
+		return
+	}
 
-	log.Debugf("lastModifyTime:%v", c.LastModifyTime)
-	return nil
+	if layout := c.existingLayout(); layout != "" && layout != c.Layout {
+		log.Infof("comic:%v, keep existing layout:%v", c.EnTitle, layout)
+		c.Layout = layout
+	}
 }
 
-func (c *Comics) WriteMetadata() error {
-	metaPath := c.getMetadataPath()
-	log.Debugf("metadata path:%v", metaPath)
+// existingLayout 优先用 manifest 里记录的布局；没有 manifest 的老下载只有 images 目录、
+// 没有 chapters 目录时是按 cdn 布局下载的
+func (c *Comics) existingLayout() string {
+	if m, err := ReadManifest(c.getMetadataPath()); err == nil && IsValidLayout(m.Layout) {
+		return m.Layout
+	}
 
-	var buf bytes.Buffer
-	buf.WriteString("标题: " + c.Title + "\n")
-	buf.WriteString("链接: " + c.MainUrl + "\n")
-	buf.WriteString("封面: " + c.CoverUrl + "\n")
-	buf.WriteString("简介: " + c.Desc + "\n")
-	buf.WriteString("更新时间: " + c.LastModifyTime + "\n")
+	if _, err := os.Stat(filepath.Join(c.RootPath, c.EnTitle, DefaultImageDataPath)); err != nil {
+		return ""
+	}
 
-	if err := c.writeFile(metaPath, buf.Bytes()); err != nil {
-		log.Errorf("write metadata failed, err:%v", err)
-		return err
+	if _, err := os.Stat(filepath.Join(c.RootPath, c.EnTitle, DefaultChapterDataPath)); os.IsNotExist(err) {
+		return LayoutCdn
 	}
 
-	log.Debugf("write metadata success.")
-	return nil
+	return ""
 }
 
 func (c *Comics) ParseMainPageUrls() error {
-	var (
-		existPages = make(map[string]bool, 8)
-		pageUrls   = make([]string, 0, 8)
-	)
-
-	pageUrls = append(pageUrls, c.MainUrl)
-	c.rootDoc.Find(".container .content-wrap .content .article-content .article-paging .post-page-numbers").Each(func(i int, s *goquery.Selection) {
-		href, exist := s.Attr("href")
-		if !exist {
-			log.Info("no href attr")
-			return
-		}
+	pageUrls, err := c.adapter.ParseChapters(c.rootDoc, c.MainUrl)
+	if err != nil {
+		return errors.Wrap(err, "parse chapters failed")
+	}
 
-		log.Infof("i:%v,href:%v", i, href)
+	for i, pageUrl := range pageUrls {
+		pageUrls[i] = c.mirrors.canonical(pageUrl)
+	}
 
-		if existPages[href] {
-			log.Infof("href:%v, already process", href)
-			return
+	c.SiteChapters = len(pageUrls)
+	c.PageUrls = make([]string, 0, len(pageUrls))
+	c.Chapters = make([]*Chapter, 0, len(pageUrls))
+	for i, pageUrl := range pageUrls {
+		// 只保留选中的章节，序号仍按站点上的顺序
+		if !c.ChapterRange.Contains(i + 1) {
+			continue
 		}
 
-		if !c.IsValidPageUrl(href) {
-			log.Infof("href:%v, invalid page url", href)
-			return
+		chapter := &Chapter{Index: i + 1, PageUrl: pageUrl}
+		c.PageUrls = append(c.PageUrls, pageUrl)
+		c.Chapters = append(c.Chapters, chapter)
+
+		pagePath, _ := c.getPageDataPath(pageUrl)
+		if err := c.journal.Pending(chapterJobKey(chapter), pageUrl, pagePath); err != nil {
+			log.Errorf("pageUrl:%v, write journal failed, err:%v", pageUrl, err)
 		}
+	}
 
-		pageUrls = append(pageUrls, href)
-		existPages[href] = true
-		log.Debugf("sub page url:%v", href)
-	})
+	if len(c.Chapters) == 0 {
+		return errors.Errorf("no chapter selected, total:%v", len(pageUrls))
+	}
 
-	c.PageUrls = pageUrls
+	log.Debugf("chapters total:%v, selected:%v", len(pageUrls), len(c.Chapters))
 	return c.writeContentMainFile()
 }
 
@@ -326,95 +474,143 @@ func (c *Comics) writeContentMainFile() error {
 		return err
 	}
 
-	log.Errorf("write data/main file success")
+	log.Debugf("write data/main file success")
 	return nil
 }
 
 func (c *Comics) GetPageUrlsContent() error {
-	for _, pageUrl := range c.PageUrls {
-		htmlContent, err := c.getPageContent(pageUrl)
+	for _, chapter := range c.Chapters {
+		pageUrl := chapter.PageUrl
+
+		htmlContent, err := c.getPageContent(chapter)
+		if ctxErr := c.baseContext().Err(); ctxErr != nil {
+			return errors.Wrap(ctxErr, "download page canceled")
+		}
+
+		if errors.Is(err, ErrRateLimited) {
+			log.Errorf("get pageUrl:%v content rate limited, abort, err:%v", pageUrl, err)
+			return errors.Wrap(err, "download page failed")
+		}
+
 		if err != nil {
 			log.Errorf("get pageUrl:%v content failed, err:%v", pageUrl, err)
+			c.failedPages++
 			continue
 		}
 
 		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(htmlContent))
 		if err != nil {
 			log.Errorf("parse pageUrl:%v content failed, err:%v", pageUrl, err)
+			c.failedPages++
 			continue
 		}
 
 		c.pageDocs[pageUrl] = doc
-		log.Debug("pageUrl:%v, parse page content success", pageUrl)
+		log.Debugf("pageUrl:%v, parse page content success", pageUrl)
 	}
 
 	return nil
 }
 
-func (c *Comics) getPageContent(pageUrl string) (htmlContent []byte, err error) {
+func (c *Comics) getPageContent(chapter *Chapter) (htmlContent []byte, err error) {
+	pageUrl := chapter.PageUrl
+	key := chapterJobKey(chapter)
+
 	pagePath, err := c.getPageDataPath(pageUrl)
 	if err != nil {
 		log.Errorf("pageUrl:%v, get page path failed, err:%v", pageUrl, err)
 		return
 	}
 
-	htmlContent, err = c.getPageContentInDisk(pagePath)
-	if err == nil {
-		log.Debugf("pageUrl:%v already in disk, no need download", pageUrl)
-		return
+	// 磁盘上的章节页只在 DefaultChapterPageTTL 内复用，过期后重新请求，开了缓存时会带上条件请求头
+	if entry, ok := c.journal.Get(key); ok && time.Since(entry.UpdatedAt) < DefaultChapterPageTTL &&
+		c.journal.IsDone(key, pageUrl, pagePath) {
+		htmlContent, err = os.ReadFile(pagePath)
+		if err == nil {
+			log.Debugf("pageUrl:%v already in disk, no need download", pageUrl)
+			return
+		}
 	}
 
-	htmlContent, err = DownloadPage(pageUrl, c.Debug)
+	htmlContent, err = c.downloadPage(pageUrl, DefaultChapterPageTTL)
 	if err != nil {
 		log.Errorf("pageUrl:%v, download failed, err:%v", pageUrl, err)
+		_ = c.journal.Failed(key, pageUrl, pagePath, err)
 		return
 	}
 
 	if err = c.writeFile(pagePath, htmlContent); err != nil {
 		log.Errorf("write file:%v failed, err:%v", pagePath, err)
+		_ = c.journal.Failed(key, pageUrl, pagePath, err)
+		return
+	}
+
+	if err = c.journal.Done(key, pageUrl, pagePath, int64(len(htmlContent)), bytesChecksum(htmlContent)); err != nil {
+		log.Errorf("pageUrl:%v, write journal failed, err:%v", pageUrl, err)
+		err = nil
 	}
 
 	log.Debugf("pageUrl:%v, download success", pageUrl)
 	return
 }
 
-func (c *Comics) getPageContentInDisk(pagePath string) ([]byte, error) {
-	stat, err := os.Stat(pagePath)
-	if err != nil {
-		return nil, err
-	}
+func (c *Comics) downloadPage(pageUrl string, ttl time.Duration) (body []byte, err error) {
+	ctx := withLimiter(WithCacheTTL(c.baseContext(), ttl), c.limiter)
+
+	for _, mirrorUrl := range c.mirrors.candidates(pageUrl) {
+		err = c.retry.DoContext(ctx, mirrorUrl, func() error {
+			body, err = DownloadPageContext(ctx, c.client, mirrorUrl, c.Debug)
+			return err
+		})
+
+		if err == nil {
+			c.mirrors.activate(mirrorUrl)
+			return
+		}
+
+		if !isFailoverError(err) {
+			return
+		}
 
-	if stat.Size() <= 0 {
-		_ = os.Remove(pagePath)
-		return nil, errors.New("empty file")
+		log.Errorf("pageUrl:%v, mirror failed, try next, err:%v", mirrorUrl, err)
 	}
 
-	return os.ReadFile(pagePath)
+	return
 }
 
 func (c *Comics) writeFile(path string, data []byte) error {
 	dir, file := filepath.Split(path)
 	log.Debugf("dir:%v, file:%v", dir, file)
 
-	if err := os.MkdirAll(dir, 0755); err != nil && !os.IsExist(err) {
-		return err
-	}
-
-	if err := os.WriteFile(path, data, 0666); err != nil {
-		return err
+	if c.dryRun {
+		log.Debugf("dry run, skip write:%v", path)
+		return nil
 	}
 
-	return nil
+	return writeFileAtomic(path, data)
 }
 
 func (c *Comics) getMetadataPath() string {
-	return filepath.Join(c.RootPath, c.EnTitle, DefaultMetadataPath, "base")
+	return GetManifestPath(c.RootPath, c.EnTitle)
+}
+
+func (c *Comics) getJournalPath() string {
+	return filepath.Join(c.RootPath, c.EnTitle, DefaultMetadataPath, DefaultJournalName)
 }
 
 func (c *Comics) getContentDataPath(fname string) string {
 	return filepath.Join(c.RootPath, c.EnTitle, DefaultContentDataPath, fname)
 }
 
+func (c *Comics) getExportDataPath(fname string) string {
+	return filepath.Join(c.RootPath, c.EnTitle, DefaultExportDataPath, fname)
+}
+
+func (c *Comics) getCoverPath() string {
+	cover := &Image{Url: c.CoverUrl}
+	return filepath.Join(c.RootPath, c.EnTitle, DefaultCoverName+filepath.Ext(cover.Name()))
+}
+
 func (c *Comics) getPageDataPath(pageUrl string) (string, error) {
 	pos := strings.LastIndex(pageUrl, "/")
 	if pos == -1 {
@@ -429,10 +625,14 @@ func (c *Comics) getPageDataPath(pageUrl string) (string, error) {
 	return filepath.Join(c.RootPath, c.EnTitle, DefaultPageDataPath, pageUrl[pos+1:]), nil
 }
 
-func (c *Comics) getImageDataPath(imageUrl string) (string, error) {
-	u, err := url.ParseRequestURI(imageUrl)
+func (c *Comics) getImageDataPath(image *Image) (string, error) {
+	if c.Layout == LayoutChapter {
+		return filepath.Join(c.RootPath, c.EnTitle, DefaultChapterDataPath, chapterName(image.Chapter), image.Name()), nil
+	}
+
+	u, err := url.ParseRequestURI(image.Url)
 	if err != nil {
-		log.Errorf("imageUrl:%v, parse failed, err:%v", imageUrl, err)
+		log.Errorf("imageUrl:%v, parse failed, err:%v", image.Url, err)
 		return "", err
 	}
 
@@ -440,178 +640,224 @@ func (c *Comics) getImageDataPath(imageUrl string) (string, error) {
 }
 
 func (c *Comics) GetImageUrls() error {
-	for pageUrl, doc := range c.pageDocs {
-		imageUrls, err := c.getImageUrl(doc)
+	for _, chapter := range c.Chapters {
+		doc, ok := c.pageDocs[chapter.PageUrl]
+		if !ok {
+			log.Errorf("pageUrl:%v, no page content", chapter.PageUrl)
+			continue
+		}
+
+		images, err := c.adapter.ParseImages(doc)
 		if err != nil {
-			log.Errorf("pageUrl:%v, get image urls from page url failed, err:%v", pageUrl, err)
+			log.Errorf("pageUrl:%v, get image urls from page url failed, err:%v", chapter.PageUrl, err)
 			continue
 		}
 
-		log.Debugf("pageUrl:%v, imageUrls:%+v", pageUrl, imageUrls)
+		chapter.Images = images
+		for i, image := range chapter.Images {
+			image.Chapter = chapter.Index
+			image.Index = i + 1
+			c.ImageUrls = append(c.ImageUrls, image.Url)
 
-		if err = c.writeContentPageFile(pageUrl, imageUrls); err != nil {
-			log.Errorf("pageUrl:%v, write image urls to file failed, err:%v", pageUrl, err)
-			continue
+			imagePath, _ := c.getImageDataPath(image)
+			if err = c.journal.Pending(imageJobKey(image), image.Url, imagePath); err != nil {
+				log.Errorf("imageUrl:%v, write journal failed, err:%v", image.Url, err)
+			}
 		}
 
-		c.ImageUrls = append(c.ImageUrls, imageUrls...)
+		log.Debugf("pageUrl:%v, image count:%v", chapter.PageUrl, len(chapter.Images))
+
+		if err = c.writeContentPageFile(chapter); err != nil {
+			log.Errorf("pageUrl:%v, write image urls to file failed, err:%v", chapter.PageUrl, err)
+			continue
+		}
 	}
 
 	return nil
 }
 
-func (c *Comics) writeContentPageFile(pageUrl string, imageUrls []string) error {
+func (c *Comics) writeContentPageFile(chapter *Chapter) error {
 	buf := bytes.Buffer{}
 
-	for _, imageUrl := range imageUrls {
-		imagePath, _ := c.getPageDataPath(imageUrl)
+	for _, image := range chapter.Images {
+		imagePath, _ := c.getImageDataPath(image)
 		buf.WriteString(imagePath + "\n")
 	}
 
-	_, file := filepath.Split(pageUrl)
+	_, file := filepath.Split(chapter.PageUrl)
 	item := strings.Split(file, ".")
 
 	return c.writeFile(c.getContentDataPath(item[0]), buf.Bytes())
 }
 
-func (c *Comics) getImageUrl(doc *goquery.Document) ([]string, error) {
-	existImages := make(map[string]bool, 8)
-	imageUrls := make([]string, 0, 8)
-
-	doc.Find(".container .content-wrap .content .article-content p img").Each(func(i int, s *goquery.Selection) {
-		src, exist := s.Attr("src")
-		if !exist {
-			log.Info("no src attr")
-			return
-		}
-
-		log.Infof("index:%v, src:%v", i, src)
-
-		if existImages[src] {
-			log.Infof("imageUrl:%v, already process", src)
-			return
-		}
+func (c *Comics) GetImagesContent() error {
+	var (
+		wg     sync.WaitGroup
+		once   sync.Once
+		mu     sync.Mutex
+		failed int
+		abort  error
+		stop   = make(chan struct{})
+		tasks  = make(chan *Image, c.Concurrency)
+	)
 
-		if !c.IsValidImageUrl(src) {
-			log.Infof("imageUrl:%v, invalid image url", src)
-			return
+	for i := 0; i < c.Concurrency; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+
+			for image := range tasks {
+				log.Debugf("worker:%v, receive image, url:%v", worker, image.Url)
+
+				err := c.getImageContent(image)
+				if err != nil {
+					mu.Lock()
+					failed++
+					mu.Unlock()
+				}
+
+				if errors.Is(err, ErrRateLimited) {
+					log.Errorf("image url:%v, rate limited, abort, err:%v", image.Url, err)
+					once.Do(func() {
+						abort = err
+						close(stop)
+					})
+					continue
+				}
+
+				if err != nil {
+					log.Errorf("image url:%v, download failed, err:%v", image.Url, err)
+					continue
+				}
+
+				log.Debugf("download image:%v success", image.Url)
+			}
+		}(i)
+	}
+
+	done := c.baseContext().Done()
+
+send:
+	for _, chapter := range c.Chapters {
+		for _, image := range chapter.Images {
+			select {
+			case tasks <- image:
+			case <-stop:
+				break send
+			case <-done:
+				break send
+			}
 		}
+	}
 
-		imageUrls = append(imageUrls, src)
-		existImages[src] = true
-	})
-
-	return imageUrls, nil
-}
+	close(tasks)
+	log.Debug("task send finish")
 
-func (c *Comics) GetImagesContent() error {
-	tasks := make(chan string, 1000)
+	wg.Wait()
+	log.Debug("task receive finish")
 
-	go func() {
-		for _, imageUrl := range c.ImageUrls {
-			tasks <- imageUrl
-		}
+	c.failedImages = failed
 
-		log.Debug("task send finish")
-		close(tasks)
-	}()
+	if abort != nil {
+		return errors.Wrap(abort, "download images failed")
+	}
 
-	for {
-		time.Sleep(2 * time.Second)
+	if err := c.baseContext().Err(); err != nil {
+		return errors.Wrap(err, "download images canceled")
+	}
 
-		imageUrl, ok := <-tasks
-		if !ok {
-			log.Debug("task receive finish")
-			break
-		}
+	return nil
+}
 
-		log.Debugf("receive image, url:%v", imageUrl)
+func (c *Comics) GetCoverContent() error {
+	if c.CoverUrl == "" {
+		return errors.New("no cover url")
+	}
 
-		if err := c.getImageContent(imageUrl); err != nil {
-			log.Errorf("image url:%v, download failed, err:%v", imageUrl, err)
-			continue
-		}
+	coverPath := c.getCoverPath()
+	if c.journal.IsDone(coverJobKey(), c.CoverUrl, coverPath) {
+		log.Infof("coverUrl:%v already exist, no need download", c.CoverUrl)
+		return nil
+	}
 
-		log.Debugf("download image:%v success", imageUrl)
+	if err := c.downloadImageContent(coverPath, c.CoverUrl); err != nil {
+		_ = c.journal.Failed(coverJobKey(), c.CoverUrl, coverPath, err)
+		return errors.Wrap(err, "download cover content failed")
 	}
 
+	c.recordDone(coverJobKey(), c.CoverUrl, coverPath)
+	log.Debugf("coverUrl:%v download content success", c.CoverUrl)
 	return nil
 }
 
-func (c *Comics) getImageContent(imageUrl string) error {
-	imagePath, err := c.getImageDataPath(imageUrl)
+func (c *Comics) getImageContent(image *Image) error {
+	imagePath, err := c.getImageDataPath(image)
 	if err != nil {
 		return errors.Wrapf(err, "get image path failed")
 	}
 
-	log.Debugf("imageUrl:%v, imagePath:%v", imageUrl, imagePath)
+	log.Debugf("imageUrl:%v, imagePath:%v", image.Url, imagePath)
 
-	if ok := c.isImageExist(imagePath); ok {
-		log.Infof("imageUrl:%v already exist, no need download", imageUrl)
+	key := imageJobKey(image)
+	if c.journal.IsDone(key, image.Url, imagePath) {
+		log.Infof("imageUrl:%v already exist, no need download", image.Url)
 		return nil
 	}
 
-	if err = c.downloadImageContent(imagePath, imageUrl); err != nil {
+	if err = c.downloadImageContent(imagePath, image.Url); err != nil {
+		_ = c.journal.Failed(key, image.Url, imagePath, err)
 		return errors.Wrap(err, "download image content failed")
 	}
 
-	log.Debugf("imageUrl:%v download content success", imageUrl)
+	c.recordDone(key, image.Url, imagePath)
+	log.Debugf("imageUrl:%v download content success", image.Url)
 	return nil
 }
 
-func (c *Comics) isImageExist(imagePath string) bool {
-	stat, err := os.Stat(imagePath)
+func (c *Comics) recordDone(key, url, path string) {
+	checksum, size, err := fileChecksum(path)
 	if err != nil {
-		return false
+		log.Errorf("path:%v, checksum failed, err:%v", path, err)
+		return
 	}
 
-	if stat.Size() <= 0 {
-		_ = os.Remove(imagePath)
-		return false
+	if c.store != nil {
+		if err = c.store.Put(path, checksum); err != nil {
+			log.Errorf("path:%v, put into store failed, err:%v", path, err)
+		}
 	}
 
-	return true
+	if err = c.journal.Done(key, url, path, size, checksum); err != nil {
+		log.Errorf("url:%v, write journal failed, err:%v", url, err)
+	}
 }
 
 func (c *Comics) downloadImageContent(imagePath, imageUrl string) error {
-	return DownloadImage(imagePath, imageUrl)
+	ctx := withLimiter(c.baseContext(), c.limiter)
+
+	return c.retry.DoContext(ctx, imageUrl, func() error {
+		// 图片本身就落在章节目录里，不再经过 http 缓存
+		return DownloadImageContext(ctx, c.client, imagePath, imageUrl)
+	})
 }
 
 func (c *Comics) IsValidPageUrl(pageUrl string) bool {
-	var (
-		pagePrefix = "page"
-		pageSuffix = ".html"
-	)
-
-	if pageUrl == c.MainUrl {
-		return true
-	}
-
-	u, err := url.ParseRequestURI(pageUrl)
-	if err != nil {
-		log.Errorf("pageUrl:%v parse failed, err:%v", pageUrl, err)
+	if c.adapter == nil {
 		return false
 	}
 
-	dir, file := filepath.Split(u.Path)
-	log.Debugf("pageUrl:%v, dir:%v, file:%v", pageUrl, dir, file)
-
-	file = strings.ToLower(file)
-	if !strings.HasPrefix(file, pagePrefix) {
-		log.Errorf("pageUrl:%v, no %v prefix", pageUrl, pagePrefix)
-		return false
-	}
+	return c.adapter.IsValidPageUrl(c.MainUrl, pageUrl)
+}
 
-	if !strings.HasSuffix(file, pageSuffix) {
-		log.Errorf("pageUrl:%v, no %v suffix", pageUrl, pageSuffix)
-		return false
+func (c *Comics) IsValidImageUrl(imageUrl string) bool {
+	if c.adapter == nil {
+		return isValidImageSuffix(imageUrl)
 	}
 
-	log.Debugf("pageUrl:%v verify success", pageUrl)
-	return true
+	return c.adapter.IsValidImageUrl(imageUrl)
 }
 
-func (c *Comics) IsValidImageUrl(imageUrl string) bool {
+func isValidImageSuffix(imageUrl string) bool {
 	u, err := url.ParseRequestURI(imageUrl)
 	if err != nil {
 		log.Errorf("imageUrl:%v parse failed, err:%v", imageUrl, err)
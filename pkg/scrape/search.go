@@ -0,0 +1,223 @@
+package scrape
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultSearchLimit = 20
+)
+
+type SearchResult struct {
+	Number   int    `json:"number"`
+	Title    string `json:"title"`
+	EnTitle  string `json:"pinyin_title"`
+	Category string `json:"category"`
+	Score    int    `json:"score"`
+	Match    string `json:"match"`
+}
+
+// 各字段的基础分，完全相同、前缀、包含依次递减
+var searchFields = []struct {
+	name   string
+	weight int
+}{
+	{"title", 100},
+	{"pinyin", 90},
+	{"initials", 80},
+	{"subtitle", 40},
+	{"category", 30},
+	{"desc", 20},
+}
+
+func (l *LibraryDB) IndexComic(c *Comics) error {
+	_, err := l.db.Exec(`INSERT OR REPLACE INTO search_index
+		(number, title, pinyin_title, initials, category, subtitle, description, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.Number, c.Title, strings.ToLower(ParseCnToEn(c.Title)), ParseCnToInitials(c.Title),
+		c.Category, c.Subtitle, c.Desc, time.Now().Format(time.RFC3339))
+
+	return errors.Wrap(err, "index comic failed")
+}
+
+// Search 按中文、全拼或拼音首字母搜索，多个词之间是且的关系，分数高的在前
+func (l *LibraryDB) Search(term string, limit int) ([]*SearchResult, error) {
+	terms := strings.Fields(strings.ToLower(term))
+	if len(terms) == 0 {
+		return nil, errors.New("empty search term")
+	}
+
+	// 先用 LIKE 在库里筛出每个词都能匹配上的漫画，只对这些打分
+	var (
+		where = make([]string, 0, len(terms))
+		args  = make([]interface{}, 0, len(terms)*len(searchFields))
+	)
+
+	for _, t := range terms {
+		pattern := "%" + likeEscaper.Replace(t) + "%"
+		where = append(where, `(s.title LIKE ? ESCAPE '\' OR s.pinyin_title LIKE ? ESCAPE '\' OR s.initials LIKE ? ESCAPE '\'
+			OR s.subtitle LIKE ? ESCAPE '\' OR s.category LIKE ? ESCAPE '\' OR s.description LIKE ? ESCAPE '\')`)
+		for range searchFields {
+			args = append(args, pattern)
+		}
+	}
+
+	rows, err := l.db.Query(`SELECT s.number, s.title, s.pinyin_title, s.initials, s.category, s.subtitle, s.description,
+		COALESCE(c.pinyin_title, s.pinyin_title) FROM search_index s LEFT JOIN comics c ON c.number = s.number
+		WHERE `+strings.Join(where, " AND "), args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "query search index failed")
+	}
+
+	defer rows.Close()
+
+	results := make([]*SearchResult, 0, 16)
+	for rows.Next() {
+		var (
+			number                                                 int
+			title, pinyinTitle, initials, category, subtitle, desc string
+			enTitle                                                string
+		)
+
+		if err = rows.Scan(&number, &title, &pinyinTitle, &initials, &category, &subtitle, &desc, &enTitle); err != nil {
+			return nil, errors.Wrap(err, "scan search index failed")
+		}
+
+		fields := map[string]string{
+			"title":    strings.ToLower(title),
+			"pinyin":   pinyinTitle,
+			"initials": initials,
+			"subtitle": strings.ToLower(subtitle),
+			"category": strings.ToLower(category),
+			"desc":     strings.ToLower(desc),
+		}
+
+		score, match := 0, ""
+		for _, t := range terms {
+			s, m := scoreTerm(fields, t)
+			if s == 0 {
+				score = 0
+				break
+			}
+
+			score += s
+			if match == "" {
+				match = m
+			}
+		}
+
+		if score == 0 {
+			continue
+		}
+
+		results = append(results, &SearchResult{
+			Number:   number,
+			Title:    title,
+			EnTitle:  enTitle,
+			Category: category,
+			Score:    score,
+			Match:    match,
+		})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+
+		return results[i].EnTitle < results[j].EnTitle
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func scoreTerm(fields map[string]string, term string) (int, string) {
+	best, match := 0, ""
+	for _, field := range searchFields {
+		value := fields[field.name]
+		if value == "" {
+			continue
+		}
+
+		score := 0
+		switch {
+		case value == term:
+			score = field.weight
+		case strings.HasPrefix(value, term):
+			score = field.weight * 3 / 4
+		case strings.Contains(value, term):
+			score = field.weight / 2
+		}
+
+		if score > best {
+			best, match = score, field.name
+		}
+	}
+
+	return best, match
+}
+
+// RebuildSearchIndex 从所有 comic.json 重建索引，用于升级前已经抓取的漫画
+func RebuildSearchIndex(rootPath string) (int, error) {
+	paths, err := ListManifests(rootPath)
+	if err != nil {
+		return 0, err
+	}
+
+	l, err := OpenLibraryDB(GetLibraryDBPath(rootPath))
+	if err != nil {
+		return 0, err
+	}
+
+	defer l.Close()
+
+	count := 0
+	for _, path := range paths {
+		c, err := LoadManifest(path)
+		if err != nil {
+			log.Errorf("manifest:%v, load failed, err:%v", path, err)
+			continue
+		}
+
+		if err = l.IndexComic(c); err != nil {
+			return count, err
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+func (c *Comics) updateSearchIndex() {
+	if c.Number == 0 || c.dryRun {
+		return
+	}
+
+	l, err := OpenLibraryDB(GetLibraryDBPath(c.RootPath))
+	if err != nil {
+		log.Errorf("open library db failed, err:%v", err)
+		return
+	}
+
+	defer l.Close()
+
+	if err = l.IndexComic(c); err != nil {
+		log.Errorf("comic:%v, update search index failed, err:%v", c.EnTitle, err)
+	}
+}
@@ -0,0 +1,111 @@
+package scrape
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestScoreTerm(t *testing.T) {
+	fields := map[string]string{
+		"title":    "致命坏男人",
+		"pinyin":   "zhiminghuainanren",
+		"initials": "zmhnr",
+		"subtitle": "",
+		"category": "韩国漫画",
+		"desc":     "一个关于男人的故事",
+	}
+
+	tests := []struct {
+		term  string
+		score int
+		match string
+	}{
+		{"致命坏男人", 100, "title"},
+		{"致命", 75, "title"},
+		{"男人", 50, "title"},
+		{"zhiminghuainanren", 90, "pinyin"},
+		{"zhiming", 67, "pinyin"},
+		{"zmhnr", 80, "initials"},
+		{"zm", 60, "initials"},
+		{"韩国", 22, "category"},
+		{"故事", 10, "desc"},
+		{"xyz", 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.term, func(t *testing.T) {
+			score, match := scoreTerm(fields, tt.term)
+			if score != tt.score || match != tt.match {
+				t.Errorf("scoreTerm(%q) = %v, %q, want %v, %q", tt.term, score, match, tt.score, tt.match)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	l, err := OpenLibraryDB(filepath.Join(t.TempDir(), DefaultLibraryDBName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	comics := []*Comics{
+		{Number: 1, Title: "致命坏男人", Category: "韩国漫画", Desc: "坏男人的故事"},
+		{Number: 2, Title: "男人的秘密", Category: "韩国漫画"},
+		{Number: 3, Title: "秘密花园", Category: "日本漫画", Subtitle: "100%_纯爱"},
+	}
+
+	for _, c := range comics {
+		if err = l.IndexComic(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		term string
+		want []int
+	}{
+		{"zmhnr", []int{1}},
+		{"男人", []int{2, 1}},
+		{"nanren", []int{2, 1}},
+		{"秘密", []int{3, 2}},
+		{"韩国 秘密", []int{2}},
+		{"MIMI", []int{3, 2}},
+		{"%_", []int{3}},
+		{"秘_", nil},
+		{"不存在", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.term, func(t *testing.T) {
+			results, err := l.Search(tt.term, DefaultSearchLimit)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]int, 0, len(results))
+			for _, r := range results {
+				got = append(got, r.Number)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("Search(%q) = %v, want %v", tt.term, got, tt.want)
+			}
+
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Search(%q) = %v, want %v", tt.term, got, tt.want)
+				}
+			}
+		})
+	}
+
+	if _, err = l.Search("  ", DefaultSearchLimit); err == nil {
+		t.Error("empty search term should fail")
+	}
+
+	if results, _ := l.Search("男人", 1); len(results) != 1 || results[0].Number != 2 {
+		t.Errorf("Search with limit 1 = %v results", len(results))
+	}
+}
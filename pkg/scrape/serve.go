@@ -0,0 +1,450 @@
+package scrape
+
+import (
+	"encoding/json"
+	"html/template"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultServeAddr    = "127.0.0.1:8080"
+	DefaultProgressName = "progress.json"
+)
+
+// Progress 阅读进度，每本漫画一份，保存在 meta/progress.json
+type Progress struct {
+	Chapter   int       `json:"chapter"`
+	Image     int       `json:"image"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func getProgressPath(rootPath, enTitle string) string {
+	return filepath.Join(rootPath, enTitle, DefaultMetadataPath, DefaultProgressName)
+}
+
+func ReadProgress(rootPath, enTitle string) (*Progress, error) {
+	data, err := os.ReadFile(getProgressPath(rootPath, enTitle))
+	if err != nil {
+		return nil, err
+	}
+
+	p := new(Progress)
+	if err = json.Unmarshal(data, p); err != nil {
+		return nil, errors.Wrap(err, "unmarshal progress failed")
+	}
+
+	return p, nil
+}
+
+func WriteProgress(rootPath, enTitle string, p *Progress) error {
+	p.UpdatedAt = time.Now()
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(getProgressPath(rootPath, enTitle), data)
+}
+
+// Server 本地阅读器，只读取 Scrape 写到磁盘上的内容，不访问网络
+type Server struct {
+	RootPath string
+	mux      *http.ServeMux
+}
+
+func NewServer(rootPath string) *Server {
+	s := &Server{RootPath: rootPath, mux: http.NewServeMux()}
+	s.mux.HandleFunc("/", s.handleLibrary)
+	s.mux.HandleFunc("/comics/", s.handleComic)
+	s.mux.HandleFunc("/api/progress/", s.handleProgress)
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debugf("serve, method:%v, path:%v", r.Method, r.URL.Path)
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) loadComic(name string) (*Comics, error) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return nil, os.ErrNotExist
+	}
+
+	return LoadManifest(GetManifestPath(s.RootPath, name))
+}
+
+// loadJournal 只读加载 journal，用来判断哪些图片已经下载完成
+func (s *Server) loadJournal(c *Comics) *Journal {
+	journal, err := ReadJournal(c.getJournalPath())
+	if err != nil {
+		log.Errorf("comic:%v, read journal failed, err:%v", c.EnTitle, err)
+	}
+
+	return journal
+}
+
+type libraryItem struct {
+	*LibraryEntry
+	Dir      string
+	Progress *Progress
+}
+
+func (s *Server) handleLibrary(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	paths, err := ListManifests(s.RootPath)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]*libraryItem, 0, len(paths))
+	for _, path := range paths {
+		c, err := LoadManifest(path)
+		if err != nil {
+			log.Errorf("manifest:%v, load failed, err:%v", path, err)
+			continue
+		}
+
+		item := &libraryItem{LibraryEntry: c.libraryEntry(), Dir: c.EnTitle}
+		item.Progress, _ = ReadProgress(s.RootPath, c.EnTitle)
+		items = append(items, item)
+	}
+
+	// 最近读过的排在前面
+	sort.SliceStable(items, func(i, j int) bool {
+		pi, pj := items[i].Progress, items[j].Progress
+		if pi == nil || pj == nil {
+			return pi != nil
+		}
+
+		return pi.UpdatedAt.After(pj.UpdatedAt)
+	})
+
+	s.render(w, "library", items)
+}
+
+func (s *Server) handleComic(w http.ResponseWriter, r *http.Request) {
+	// /comics/<dir>/、/comics/<dir>/cover、/comics/<dir>/chapters/<n>、/comics/<dir>/images/<n>/<i>
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/comics/"), "/"), "/")
+
+	c, err := s.loadComic(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		s.serveChapters(w, r, c)
+	case len(parts) == 2 && parts[1] == "cover":
+		http.ServeFile(w, r, c.getCoverPath())
+	case len(parts) == 3 && parts[1] == "chapters":
+		s.serveReader(w, r, c, parts[2])
+	case len(parts) == 4 && parts[1] == "images":
+		s.serveImage(w, r, c, parts[2], parts[3])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+type chapterItem struct {
+	Index      int
+	Images     int
+	Downloaded int
+}
+
+func (s *Server) serveChapters(w http.ResponseWriter, r *http.Request, c *Comics) {
+	journal := s.loadJournal(c)
+	chapters := make([]*chapterItem, 0, len(c.Chapters))
+	for _, chapter := range c.Chapters {
+		item := &chapterItem{Index: chapter.Index, Images: len(chapter.Images)}
+		for _, image := range chapter.Images {
+			if s.isImageOnDisk(c, journal, image) {
+				item.Downloaded++
+			}
+		}
+
+		chapters = append(chapters, item)
+	}
+
+	progress, _ := ReadProgress(s.RootPath, c.EnTitle)
+	s.render(w, "comic", map[string]interface{}{
+		"Comic":    c,
+		"Chapters": chapters,
+		"Progress": progress,
+	})
+}
+
+func (s *Server) serveReader(w http.ResponseWriter, r *http.Request, c *Comics, index string) {
+	num, err := strconv.Atoi(index)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var (
+		chapter    *Chapter
+		prev, next int
+	)
+
+	for i, ch := range c.Chapters {
+		if ch.Index != num {
+			continue
+		}
+
+		chapter = ch
+		if i > 0 {
+			prev = c.Chapters[i-1].Index
+		}
+
+		if i+1 < len(c.Chapters) {
+			next = c.Chapters[i+1].Index
+		}
+	}
+
+	if chapter == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	journal := s.loadJournal(c)
+	images := make([]*Image, 0, len(chapter.Images))
+	for _, image := range chapter.Images {
+		if s.isImageOnDisk(c, journal, image) {
+			images = append(images, image)
+		}
+	}
+
+	resume := 0
+	if p, err := ReadProgress(s.RootPath, c.EnTitle); err == nil && p.Chapter == chapter.Index {
+		resume = p.Image
+	}
+
+	s.render(w, "reader", map[string]interface{}{
+		"Comic":   c,
+		"Chapter": chapter,
+		"Images":  images,
+		"Prev":    prev,
+		"Next":    next,
+		"Resume":  resume,
+	})
+}
+
+func (s *Server) serveImage(w http.ResponseWriter, r *http.Request, c *Comics, chapterIndex, imageIndex string) {
+	ci, err1 := strconv.Atoi(chapterIndex)
+	ii, err2 := strconv.Atoi(imageIndex)
+	if err1 != nil || err2 != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	for _, chapter := range c.Chapters {
+		if chapter.Index != ci {
+			continue
+		}
+
+		for _, image := range chapter.Images {
+			if image.Index != ii {
+				continue
+			}
+
+			imagePath, err := c.getImageDataPath(image)
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+
+			// 图片下载后不会再变，让浏览器长期缓存
+			w.Header().Set("Cache-Control", "public, max-age=86400")
+			http.ServeFile(w, r, imagePath)
+			return
+		}
+	}
+
+	http.NotFound(w, r)
+}
+
+func (s *Server) isImageOnDisk(c *Comics, journal *Journal, image *Image) bool {
+	imagePath, err := c.getImageDataPath(image)
+	if err != nil {
+		return false
+	}
+
+	return journal.IsDoneQuick(imageJobKey(image), image.Url, imagePath)
+}
+
+func (s *Server) handleProgress(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/progress/"), "/")
+	c, err := s.loadComic(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		p, err := ReadProgress(s.RootPath, c.EnTitle)
+		if err != nil {
+			p = new(Progress)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(p)
+	case http.MethodPost, http.MethodPut:
+		// 只接受 json，跨站页面不能不经预检就发这种请求，避免其他网页改写阅读进度
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+			http.Error(w, "content type must be application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		p := new(Progress)
+		if err = json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(p); err != nil || p.Chapter <= 0 {
+			http.Error(w, "invalid progress", http.StatusBadRequest)
+			return
+		}
+
+		if err = WriteProgress(s.RootPath, c.EnTitle, p); err != nil {
+			log.Errorf("comic:%v, write progress failed, err:%v", c.EnTitle, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, POST, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) render(w http.ResponseWriter, name string, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := serveTemplates.ExecuteTemplate(w, name, data); err != nil {
+		log.Errorf("render template:%v failed, err:%v", name, err)
+	}
+}
+
+var serveTemplates = template.Must(template.New("").Funcs(template.FuncMap{
+	"size": func(size int64) string {
+		const unit = 1024
+		if size < unit {
+			return strconv.FormatInt(size, 10) + "B"
+		}
+
+		value, units := float64(size)/unit, "KMGTPE"
+		i := 0
+		for ; value >= unit && i < len(units)-1; i++ {
+			value /= unit
+		}
+
+		return strconv.FormatFloat(value, 'f', 1, 64) + string(units[i]) + "iB"
+	},
+}).Parse(serveTemplateText))
+
+const serveTemplateText = `
+{{define "head"}}<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.}}</title>
+<style>
+body { margin: 0; font-family: sans-serif; background: #111; color: #ddd; }
+a { color: #8cf; text-decoration: none; }
+header { padding: 12px 16px; background: #222; }
+main { padding: 16px; }
+.grid { display: grid; grid-template-columns: repeat(auto-fill, minmax(160px, 1fr)); gap: 16px; }
+.card img { width: 100%; aspect-ratio: 3 / 4; object-fit: cover; background: #333; }
+.card .meta { font-size: 12px; color: #999; }
+.chapters { list-style: none; padding: 0; display: grid; grid-template-columns: repeat(auto-fill, minmax(120px, 1fr)); gap: 8px; }
+.chapters li { background: #222; padding: 8px; }
+.chapters li.current { outline: 2px solid #8cf; }
+.reader { max-width: 800px; margin: 0 auto; }
+.reader img { display: block; width: 100%; height: auto; }
+nav { display: flex; justify-content: space-between; padding: 12px 16px; background: #222; }
+</style>
+</head>
+<body>{{end}}
+
+{{define "library"}}{{template "head" "sansi"}}
+<header><strong>sansi</strong> · {{len .}} comics</header>
+<main><div class="grid">
+{{range .}}<a class="card" href="/comics/{{.Dir}}/">
+<img src="/comics/{{.Dir}}/cover" alt="{{.Title}}" loading="lazy">
+<div>{{.Title}}</div>
+<div class="meta">{{.DownloadedChapters}}/{{.SiteChapters}} chapters · {{size .Size}}{{if .Progress}} · read to {{.Progress.Chapter}}{{end}}</div>
+</a>
+{{end}}</div></main>
+</body></html>{{end}}
+
+{{define "comic"}}{{template "head" .Comic.Title}}
+<header><a href="/">library</a> / <strong>{{.Comic.Title}}</strong></header>
+<main>
+<p>{{.Comic.Subtitle}} {{.Comic.Category}} {{.Comic.LastModifyTime}}</p>
+<p>{{.Comic.Desc}}</p>
+{{if .Progress}}<p><a href="/comics/{{.Comic.EnTitle}}/chapters/{{.Progress.Chapter}}">continue chapter {{.Progress.Chapter}}</a></p>{{end}}
+<ul class="chapters">
+{{$progress := .Progress}}{{$dir := .Comic.EnTitle}}
+{{range .Chapters}}<li{{if $progress}}{{if eq $progress.Chapter .Index}} class="current"{{end}}{{end}}>
+<a href="/comics/{{$dir}}/chapters/{{.Index}}">chapter {{.Index}}</a>
+<div class="meta">{{.Downloaded}}/{{.Images}}</div>
+</li>
+{{end}}</ul>
+</main>
+</body></html>{{end}}
+
+{{define "reader"}}{{template "head" .Comic.Title}}
+{{$dir := .Comic.EnTitle}}
+<nav>
+<span>{{if .Prev}}<a href="/comics/{{$dir}}/chapters/{{.Prev}}">prev</a>{{end}}</span>
+<span><a href="/comics/{{$dir}}/">{{.Comic.Title}}</a> · chapter {{.Chapter.Index}}</span>
+<span>{{if .Next}}<a href="/comics/{{$dir}}/chapters/{{.Next}}">next</a>{{end}}</span>
+</nav>
+<div class="reader">
+{{range .Images}}<img id="p{{.Index}}" data-index="{{.Index}}" src="/comics/{{$dir}}/images/{{.Chapter}}/{{.Index}}" loading="lazy"{{if .Width}} width="{{.Width}}" height="{{.Height}}"{{end}}>
+{{end}}</div>
+<nav>
+<span>{{if .Prev}}<a href="/comics/{{$dir}}/chapters/{{.Prev}}">prev</a>{{end}}</span>
+<span>{{if .Next}}<a href="/comics/{{$dir}}/chapters/{{.Next}}">next</a>{{end}}</span>
+</nav>
+<script>
+(function () {
+	var api = "/api/progress/{{$dir}}", chapter = {{.Chapter.Index}}, resume = {{.Resume}}, last = 0, timer = null;
+	if (resume > 0) {
+		var el = document.getElementById("p" + resume);
+		if (el) { el.scrollIntoView(); }
+	}
+	function save(image) {
+		if (image === last) { return; }
+		last = image;
+		clearTimeout(timer);
+		timer = setTimeout(function () {
+			fetch(api, {method: "POST", headers: {"Content-Type": "application/json"}, body: JSON.stringify({chapter: chapter, image: image})});
+		}, 500);
+	}
+	var observer = new IntersectionObserver(function (entries) {
+		entries.forEach(function (e) {
+			if (e.isIntersecting) { save(parseInt(e.target.dataset.index, 10)); }
+		});
+	}, {threshold: 0.5});
+	document.querySelectorAll(".reader img").forEach(function (img) { observer.observe(img); });
+	save(resume > 0 ? resume : 1);
+})();
+</script>
+</body></html>{{end}}
+`
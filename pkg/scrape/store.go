@@ -0,0 +1,228 @@
+package scrape
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultStoreDir = ".store"
+	StoreHardlink   = "hardlink"
+	StoreReflink    = "reflink"
+)
+
+// BlobStore 按 sha256 保存图片，各漫画的章节目录里只放指向 blob 的硬链接或 reflink，
+// 相同的图片在整个库里只存一份
+type BlobStore struct {
+	Dir  string
+	Link string
+}
+
+type GcResult struct {
+	Blobs   int   `json:"blobs"`
+	Removed int   `json:"removed"`
+	Freed   int64 `json:"freed"`
+}
+
+func GetStorePath(rootPath string) string {
+	return filepath.Join(rootPath, DefaultStoreDir)
+}
+
+func OpenStore(dir, link string) (*BlobStore, error) {
+	if link == "" {
+		link = StoreHardlink
+	}
+
+	if link != StoreHardlink && link != StoreReflink {
+		return nil, errors.Errorf("unsupported store link:%v", link)
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, errors.Wrap(err, "create store dir failed")
+	}
+
+	return &BlobStore{Dir: dir, Link: link}, nil
+}
+
+func (s *BlobStore) blobPath(checksum string) string {
+	return filepath.Join(s.Dir, checksum[:2], checksum)
+}
+
+// Put 把刚下载好的文件收进仓库，已有相同内容的 blob 时把 path 换成指向它的链接，
+// 多个 worker 同时放入同一个 blob 时只有一个能链接成功，其余的校验已有 blob 后直接复用
+func (s *BlobStore) Put(path, checksum string) error {
+	if len(checksum) < 2 {
+		return errors.Errorf("invalid checksum:%v", checksum)
+	}
+
+	blob := s.blobPath(checksum)
+	if err := os.MkdirAll(filepath.Dir(blob), os.ModePerm); err != nil {
+		return errors.Wrap(err, "create blob dir failed")
+	}
+
+	err := s.link(path, blob)
+	if err == nil {
+		return nil
+	}
+
+	if !os.IsExist(err) {
+		log.Infof("path:%v, link into store failed, copy instead, err:%v", path, err)
+		if err = copyFile(path, blob); err != nil {
+			return errors.Wrap(err, "copy into store failed")
+		}
+
+		return nil
+	}
+
+	if same, _ := sameFile(path, blob); same {
+		return nil
+	}
+
+	// 已有的 blob 内容不对时用新文件替换，不把坏数据链接到漫画目录
+	if sum, _, err := fileChecksum(blob); err != nil || sum != checksum {
+		log.Errorf("blob:%v, checksum mismatch:%v, replace it, err:%v", checksum, sum, err)
+		if err = copyFile(path, blob); err != nil {
+			return errors.Wrap(err, "replace corrupt blob failed")
+		}
+
+		return nil
+	}
+
+	dir, file := filepath.Split(path)
+	tmpPath := filepath.Join(dir, "."+file+".link")
+	_ = os.Remove(tmpPath)
+
+	if err = s.link(blob, tmpPath); err != nil {
+		log.Infof("path:%v, link to blob failed, keep own copy, err:%v", path, err)
+		return nil
+	}
+
+	if err = os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return errors.Wrap(err, "replace with blob link failed")
+	}
+
+	log.Debugf("path:%v, dedup to blob:%v", path, checksum)
+	return nil
+}
+
+func (s *BlobStore) link(src, dst string) error {
+	if s.Link == StoreReflink {
+		return reflink(src, dst)
+	}
+
+	return os.Link(src, dst)
+}
+
+// Gc 删除没有被任何漫画 journal 引用的 blob，journal 里 done 状态的 sha256 就是引用
+func (s *BlobStore) Gc(rootPath string, dryRun bool) (*GcResult, error) {
+	refs, err := journalChecksums(rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(GcResult)
+	err = filepath.Walk(s.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			return err
+		}
+
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+
+		result.Blobs++
+		if refs[info.Name()] {
+			return nil
+		}
+
+		// 漫画目录里还有硬链接指向它时删掉 blob 并不释放空间
+		result.Removed++
+		if linkCount(info) == 1 {
+			result.Freed += info.Size()
+		}
+		if dryRun {
+			log.Infof("blob:%v, unreferenced", info.Name())
+			return nil
+		}
+
+		if err = os.Remove(path); err != nil {
+			log.Errorf("blob:%v, remove failed, err:%v", path, err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return result, errors.Wrap(err, "walk store dir failed")
+	}
+
+	return result, nil
+}
+
+func journalChecksums(rootPath string) (map[string]bool, error) {
+	paths, err := ListManifests(rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string]bool, 1024)
+	for _, path := range paths {
+		journalPath := filepath.Join(filepath.Dir(path), DefaultJournalName)
+
+		j, err := ReadJournal(journalPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read journal:%v failed", journalPath)
+		}
+
+		for _, entry := range j.Entries() {
+			if entry.State == JobDone && entry.Checksum != "" {
+				refs[entry.Checksum] = true
+			}
+		}
+	}
+
+	return refs, nil
+}
+
+func sameFile(a, b string) (bool, error) {
+	sa, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+
+	sb, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+
+	return os.SameFile(sa, sb), nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+
+	defer in.Close()
+
+	fd, err := createTempFile(dst)
+	if err != nil {
+		return err
+	}
+
+	return commitTempFile(fd, dst, func(w io.Writer) error {
+		_, err := io.Copy(w, in)
+		return err
+	})
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package scrape
+
+import (
+	"os"
+)
+
+// linkCount 拿不到硬链接数的平台按只有一个链接算
+func linkCount(info os.FileInfo) uint64 {
+	return 1
+}
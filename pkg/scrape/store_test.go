@@ -0,0 +1,139 @@
+package scrape
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestBlobStorePut(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenStore(filepath.Join(dir, DefaultStoreDir), StoreHardlink)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("same image")
+	checksum := bytesChecksum(data)
+
+	// 多个 worker 同时放入相同内容，最后都应该链接到同一个 blob
+	paths := make([]string, 8)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, "chapter", string(rune('a'+i))+".jpg")
+		if err = os.MkdirAll(filepath.Dir(paths[i]), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+
+		if err = os.WriteFile(paths[i], data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(paths))
+	for i, path := range paths {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			errs[i] = s.Put(path, checksum)
+		}(i, path)
+	}
+
+	wg.Wait()
+
+	for i, path := range paths {
+		if errs[i] != nil {
+			t.Fatalf("Put(%v) err = %v", path, errs[i])
+		}
+
+		if same, _ := sameFile(path, s.blobPath(checksum)); !same {
+			t.Errorf("Put(%v) not linked to blob", path)
+		}
+	}
+
+	// 再放一次不报错
+	if err = s.Put(paths[0], checksum); err != nil {
+		t.Errorf("Put() again err = %v", err)
+	}
+
+	// blob 内容被破坏时用新文件替换
+	corrupt := filepath.Join(dir, "other.jpg")
+	if err = os.WriteFile(corrupt, []byte("other image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	otherSum := bytesChecksum([]byte("other image"))
+	if err = os.MkdirAll(filepath.Dir(s.blobPath(otherSum)), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.WriteFile(s.blobPath(otherSum), []byte("broken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.Put(corrupt, otherSum); err != nil {
+		t.Fatalf("Put() corrupt blob err = %v", err)
+	}
+
+	if sum, _, _ := fileChecksum(s.blobPath(otherSum)); sum != otherSum {
+		t.Errorf("corrupt blob not replaced, checksum:%v", sum)
+	}
+}
+
+func TestBlobStoreGcFreed(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenStore(GetStorePath(dir), StoreHardlink)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	put := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		checksum := bytesChecksum([]byte(content))
+		if err := s.Put(path, checksum); err != nil {
+			t.Fatal(err)
+		}
+
+		return path
+	}
+
+	// linked 仍有漫画目录里的硬链接，orphan 只剩 blob 自己
+	put("linked.jpg", "linked")
+	orphan := put("orphan.jpg", "orphan!")
+	if err = os.Remove(orphan); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.Gc(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Blobs != 2 || result.Removed != 2 {
+		t.Errorf("Gc() = %+v, want 2 blobs 2 removed", result)
+	}
+
+	if linkCount(mustStat(t, s.blobPath(bytesChecksum([]byte("linked"))))) == 1 {
+		t.Skip("link count not supported on this platform")
+	}
+
+	if result.Freed != int64(len("orphan!")) {
+		t.Errorf("Gc() freed = %v, want %v", result.Freed, len("orphan!"))
+	}
+}
+
+func mustStat(t *testing.T, path string) os.FileInfo {
+	t.Helper()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return info
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package scrape
+
+import (
+	"os"
+	"syscall"
+)
+
+// linkCount 返回文件的硬链接数
+func linkCount(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Nlink)
+	}
+
+	return 1
+}
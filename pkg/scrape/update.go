@@ -0,0 +1,202 @@
+package scrape
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type UpdateResult struct {
+	Title             string    `json:"title"`
+	EnTitle           string    `json:"pinyin_title"`
+	Number            int       `json:"number"`
+	Url               string    `json:"url"`
+	OldLastModifyTime string    `json:"old_last_modified"`
+	NewLastModifyTime string    `json:"new_last_modified"`
+	OldChapters       int       `json:"old_chapters"`
+	NewChapters       int       `json:"new_chapters"`
+	AddedChapters     []int     `json:"added_chapters"`
+	ResumedChapters   []int     `json:"resumed_chapters,omitempty"`
+	DownloadedImages  int       `json:"downloaded_images"`
+	CheckedAt         time.Time `json:"checked_at"`
+	Error             string    `json:"error,omitempty"`
+}
+
+func (r *UpdateResult) Changed() bool {
+	return len(r.AddedChapters) > 0 || len(r.ResumedChapters) > 0 || r.OldLastModifyTime != r.NewLastModifyTime
+}
+
+func (r *UpdateResult) String() string {
+	if r.Error != "" {
+		return fmt.Sprintf("%v(%v): check failed, %v", r.Title, r.EnTitle, r.Error)
+	}
+
+	if !r.Changed() {
+		return fmt.Sprintf("%v(%v): no change, last modified:%v, chapters:%v", r.Title, r.EnTitle, r.NewLastModifyTime, r.NewChapters)
+	}
+
+	return fmt.Sprintf("%v(%v): last modified:%v -> %v, chapters:%v -> %v, new chapters:[%v], resumed chapters:[%v], downloaded images:%v",
+		r.Title, r.EnTitle, r.OldLastModifyTime, r.NewLastModifyTime, r.OldChapters, r.NewChapters,
+		joinIndexes(r.AddedChapters), joinIndexes(r.ResumedChapters), r.DownloadedImages)
+}
+
+func joinIndexes(indexes []int) string {
+	items := make([]string, 0, len(indexes))
+	for _, index := range indexes {
+		items = append(items, fmt.Sprint(index))
+	}
+
+	return strings.Join(items, ",")
+}
+
+// Update 需要先通过 RestoreManifest 载入已下载的漫画，只重新抓取首页，新增的章节和以前没下载完的章节才会下载
+func (c *Comics) Update(download bool) (result *UpdateResult, err error) {
+	defer c.Close()
+
+	if c.EnTitle == "" {
+		return nil, errors.New("no manifest loaded")
+	}
+
+	if download {
+		record := &ScrapeRecord{StartedAt: time.Now()}
+		defer func() {
+			record.FinishedAt = time.Now()
+			record.Error = err
+			c.recordLibrary(record)
+		}()
+	}
+
+	result = &UpdateResult{
+		Title:             c.Title,
+		EnTitle:           c.EnTitle,
+		Number:            c.Number,
+		Url:               c.MainUrl,
+		OldLastModifyTime: c.LastModifyTime,
+		OldChapters:       c.lastSiteChapters(),
+		CheckedAt:         time.Now(),
+	}
+
+	known := c.Chapters
+	lastSite := result.OldChapters
+	enTitle := c.EnTitle
+	if err := c.Validity(); err != nil {
+		return result, err
+	}
+
+	if err := c.GetMainContent(); err != nil {
+		return result, err
+	}
+
+	if err := c.ParseMainBasicInfo(); err != nil {
+		return result, err
+	}
+
+	// 标题的拼音可能随标题改动而变化，目录仍沿用已有的
+	c.EnTitle = enTitle
+	result.NewLastModifyTime = c.LastModifyTime
+
+	if err := c.openJournal(); err != nil {
+		return result, err
+	}
+
+	if err := c.ParseMainPageUrls(); err != nil {
+		return result, err
+	}
+
+	result.NewChapters = c.SiteChapters
+
+	// 上次站点上就有但 manifest 里没有的章节是抓取时没有选中的，不算新章节；
+	// 超出上次站点章节数的，或者这次用 --chapters 明确选中的才下载
+	downloaded := c.downloadedChapters(known)
+	added := make([]*Chapter, 0, 4)
+	for _, chapter := range c.Chapters {
+		done, ok := downloaded[chapter.Index]
+		switch {
+		case done:
+			continue
+		case ok:
+			result.ResumedChapters = append(result.ResumedChapters, chapter.Index)
+		case chapter.Index > lastSite || len(c.ChapterRange) > 0:
+			result.AddedChapters = append(result.AddedChapters, chapter.Index)
+		default:
+			continue
+		}
+
+		added = append(added, chapter)
+	}
+
+	log.Debugf("comic:%v, added chapters:%v, resumed chapters:%v", c.EnTitle, result.AddedChapters, result.ResumedChapters)
+
+	if !download || !result.Changed() {
+		return result, nil
+	}
+
+	c.Chapters = added
+	if err := c.GetPageUrlsContent(); err != nil {
+		return result, err
+	}
+
+	if err := c.GetImageUrls(); err != nil {
+		return result, err
+	}
+
+	if err := c.WriteMetadata(); err != nil {
+		return result, err
+	}
+
+	if err := c.GetCoverContent(); err != nil {
+		log.Errorf("get cover content failed, err:%v", err)
+	}
+
+	if err := c.GetImagesContent(); err != nil {
+		return result, err
+	}
+
+	// 只统计这次下载的，续传章节里以前已完成的图片不算
+	for _, chapter := range added {
+		for _, image := range chapter.Images {
+			entry, ok := c.journal.Get(imageJobKey(image))
+			if ok && entry.State == JobDone && entry.Url == image.Url && !entry.UpdatedAt.Before(result.CheckedAt) {
+				result.DownloadedImages++
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// lastSiteChapters 上次抓取时站点上的章节数，旧的 manifest 没有记录时按已知的最大章节序号算
+func (c *Comics) lastSiteChapters() int {
+	last := c.SiteChapters
+	for _, chapter := range c.Chapters {
+		if chapter.Index > last {
+			last = chapter.Index
+		}
+	}
+
+	return last
+}
+
+// downloadedChapters 按 journal 判断章节是否下载完成，manifest 里的图片列表在下载前就已写入，不能作为依据。
+// 返回值里没有的章节是 manifest 里没有的，值为 false 的是中断或有图片失败的。
+// watch 每轮都会检查整个库，这里只比对地址和大小，不重新计算校验和，完整校验留给下载时做
+func (c *Comics) downloadedChapters(chapters []*Chapter) map[int]bool {
+	downloaded := make(map[int]bool, len(chapters))
+	for _, chapter := range chapters {
+		done := len(chapter.Images) > 0
+		for _, image := range chapter.Images {
+			imagePath, err := c.getImageDataPath(image)
+			if err != nil || !c.journal.IsDoneQuick(imageJobKey(image), image.Url, imagePath) {
+				done = false
+				break
+			}
+		}
+
+		downloaded[chapter.Index] = done
+	}
+
+	return downloaded
+}
@@ -1,6 +1,8 @@
 package scrape
 
 import (
+	"strings"
+
 	"github.com/mozillazg/go-pinyin"
 )
 
@@ -17,3 +19,19 @@ func ParseCnToEn(cn string) string {
 
 	return pinyin.Slug(cn, a)
 }
+
+// ParseCnToInitials 取拼音首字母，如 致命坏男人 -> zmhnr
+func ParseCnToInitials(cn string) string {
+	a := pinyin.NewArgs()
+	a.Style = pinyin.FirstLetter
+	a.Separator = ""
+	a.Fallback = func(r rune, a pinyin.Args) []string {
+		if string(r) == " " {
+			return []string{}
+		}
+
+		return []string{string(r)}
+	}
+
+	return strings.ToLower(pinyin.Slug(cn, a))
+}
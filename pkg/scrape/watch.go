@@ -0,0 +1,166 @@
+package scrape
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultFeedName      = "feed.jsonl"
+	DefaultWatchInterval = 6 * time.Hour
+	DefaultWatchJobs     = 2
+)
+
+type Watcher struct {
+	Config   *Config
+	Interval time.Duration
+	Jobs     int
+	Download bool
+	FeedPath string
+
+	client  *http.Client
+	limiter *hostLimiter
+	feedMu  sync.Mutex
+}
+
+func NewWatcher(cfg *Config) *Watcher {
+	return &Watcher{
+		Config:   cfg,
+		Interval: DefaultWatchInterval,
+		Jobs:     DefaultWatchJobs,
+		Download: true,
+		FeedPath: filepath.Join(cfg.RootPath, DefaultFeedName),
+	}
+}
+
+func (w *Watcher) Run(ctx context.Context) error {
+	for {
+		if _, err := w.RunOnce(ctx); err != nil {
+			log.Errorf("watch round failed, err:%v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(w.Interval):
+		}
+	}
+}
+
+func (w *Watcher) RunOnce(ctx context.Context) ([]*UpdateResult, error) {
+	if w.client == nil {
+		client, err := NewHttpClient(w.Config.ClientOptions())
+		if err != nil {
+			return nil, errors.Wrap(err, "create http client failed")
+		}
+
+		w.client = client
+	}
+
+	// 和 Batch 一样所有漫画共用一个限速器，--jobs 不会放大对站点的请求频率
+	if w.limiter == nil {
+		rate := w.Config.RateLimit
+		if rate == 0 {
+			rate = DefaultRateLimit
+		}
+
+		w.limiter = newHostLimiter(rate)
+	}
+
+	paths, err := ListManifests(w.Config.RootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := w.Jobs
+	if jobs <= 0 {
+		jobs = 1
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		sem     = make(chan struct{}, jobs)
+		results = make([]*UpdateResult, 0, len(paths))
+	)
+
+	for _, path := range paths {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return results, ctx.Err()
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(path string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			result := w.check(ctx, path)
+			if err := w.appendFeed(result); err != nil {
+				log.Errorf("append feed failed, err:%v", err)
+			}
+
+			mu.Lock()
+			results = append(results, result)
+			mu.Unlock()
+		}(path)
+	}
+
+	wg.Wait()
+	return results, nil
+}
+
+func (w *Watcher) check(ctx context.Context, path string) *UpdateResult {
+	c := NewWithConfig(w.Config)
+	c.SetHttpClient(w.client)
+	c.SetContext(ctx)
+	c.limiter = w.limiter
+
+	if err := c.RestoreManifest(path); err != nil {
+		return &UpdateResult{Url: path, CheckedAt: time.Now(), Error: err.Error()}
+	}
+
+	result, err := c.Update(w.Download)
+	if result == nil {
+		result = &UpdateResult{Title: c.Title, EnTitle: c.EnTitle, Number: c.Number, Url: c.MainUrl, CheckedAt: time.Now()}
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	log.Infof("watch, %v", result)
+	return result
+}
+
+func (w *Watcher) appendFeed(result *UpdateResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	w.feedMu.Lock()
+	defer w.feedMu.Unlock()
+
+	fd, err := os.OpenFile(w.FeedPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+
+	defer fd.Close()
+
+	_, err = fd.Write(append(data, '\n'))
+	return err
+}